@@ -2,20 +2,34 @@ package main
 
 import (
 	"context"
-	"fmt"
+	"os"
+	"os/signal"
 	"runtime"
+	"syscall"
 	"time"
 
 	monitoring "cloud.google.com/go/monitoring/apiv3/v2"
+	"github.com/heraque/alloydb-autoscaler/internal/alloydb"
 	"github.com/heraque/alloydb-autoscaler/internal/config"
+	"github.com/heraque/alloydb-autoscaler/internal/leader"
 	"github.com/heraque/alloydb-autoscaler/internal/log"
 	"github.com/heraque/alloydb-autoscaler/internal/metrics"
-	"github.com/heraque/alloydb-autoscaler/internal/scaling"
+	"github.com/heraque/alloydb-autoscaler/internal/server"
+	"github.com/heraque/alloydb-autoscaler/internal/state"
 )
 
 const AppName = "AlloyDB Autoscaler"
 
 func main() {
+	err := config.Load()
+	log.Initialize()
+	if err != nil {
+		log.Fatal().Err(err).Msg("Falha ao carregar configuração")
+	}
+	if level := config.Get().LogLevel; level != "" {
+		log.SetLevel(level)
+	}
+
 	log.Info().
 		Str("component", "app").
 		Str("action", "startup").
@@ -23,7 +37,17 @@ func main() {
 		Str("version", runtime.Version()).
 		Msg("Application started successfully")
 
-	ctx := context.Background()
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGTERM, os.Interrupt)
+	defer stop()
+
+	if err := state.Open(config.Get().StateFile); err != nil {
+		log.Fatal().
+			Str("component", "app").
+			Str("action", "initialize").
+			Err(err).
+			Msg("Failed to open state file")
+	}
+
 	client, err := monitoring.NewMetricClient(ctx)
 	if err != nil {
 		log.Fatal().
@@ -34,112 +58,81 @@ func main() {
 	}
 	defer client.Close()
 
-	var scaleUpCount, scaleDownCount int
-	evaluationStart := time.Now()
-	cycleCount := 0
+	instanceAPI, err := alloydb.NewInstanceAPI(config.Get().GoogleApplicationCredentials)
+	if err != nil {
+		log.Fatal().
+			Str("component", "app").
+			Str("action", "initialize").
+			Err(err).
+			Msg("Failed to create AlloyDB client")
+	}
 
-	baseCtx := context.Background()
-	for {
-		cycleCount++
-		cycleStartTime := time.Now()
+	// As novas tentativas ficam por fora da instrumentação, que registra a
+	// latência e o erro de cada tentativa
+	source := metrics.ResilientSource(metrics.InstrumentSource(metrics.NewMonitoringSource(client)))
+	api := alloydb.Resilient(alloydb.Instrument(instanceAPI))
 
-		func() {
-			ctx, cancel := context.WithTimeout(baseCtx, time.Duration(config.Get().TimeoutSeconds)*time.Second)
-			defer cancel()
+	if addr := config.Get().HTTPAddr; addr != "" {
+		server.Start(ctx, addr)
+	}
 
-			log.Debug().
+	var elector *leader.Elector
+	if le := config.Get().LeaderElection; le.Backend != "" {
+		backend, err := leader.NewBackend(ctx, le, config.Get().GoogleApplicationCredentials)
+		if err != nil {
+			log.Fatal().
 				Str("component", "app").
-				Str("action", "check").
-				Int("cycle", cycleCount).
-				Msg("Starting metrics check cycle")
-
-			newScaleUpCount, newScaleDownCount, err := metrics.CheckMetrics(ctx, client, scaleUpCount, scaleDownCount)
-			if err != nil {
-				if ctx.Err() == context.DeadlineExceeded {
-					log.ErrorMessage("Metrics check timeout").
-						Str("component", "app").
-						Str("action", "check").
-						Int("timeoutSeconds", config.Get().TimeoutSeconds).
-						Int("cycle", cycleCount).
-						Send()
-				} else {
-					log.Error(err).
-						Str("component", "app").
-						Str("action", "check").
-						Int("cycle", cycleCount).
-						Msg("Error checking metrics")
-				}
-			} else {
-				scaleUpCount = newScaleUpCount
-				scaleDownCount = newScaleDownCount
-			}
-
-			log.Debug().
-				Str("component", "app").
-				Str("action", "check").
-				Int("cycle", cycleCount).
-				Str("duration", fmt.Sprintf("%.2fs", time.Since(cycleStartTime).Seconds())).
-				Msg("Metrics check cycle completed")
-		}()
-
-		evalElapsed := time.Since(evaluationStart)
-		if evalElapsed >= time.Duration(config.Get().Evaluation)*time.Second {
-			log.Info().
-				Str("component", "scaling").
-				Str("action", "decision").
-				Int("scaleUpVotes", scaleUpCount).
-				Int("scaleDownVotes", scaleDownCount).
-				Str("evaluationPeriod", fmt.Sprintf("%.2fs", evalElapsed.Seconds())).
-				Msg("Making scaling decision")
-
-			if scaleUpCount > scaleDownCount && scaleUpCount > 0 {
-				if err := scaling.ScaleUp(context.Background()); err != nil {
-					log.Error(err).
-						Str("component", "scaling").
-						Str("action", "scaleUp").
-						Msg("Failed to scale up replicas")
-				} else {
-					log.Info().
-						Str("component", "scaling").
-						Str("action", "scaleUp").
-						Msg("Scale up operation completed successfully")
-				}
-			} else if scaleDownCount > scaleUpCount && scaleDownCount > 0 {
-				if err := scaling.ScaleDown(context.Background()); err != nil {
-					log.Error(err).
-						Str("component", "scaling").
-						Str("action", "scaleDown").
-						Msg("Failed to scale down replicas")
-				} else {
-					log.Info().
-						Str("component", "scaling").
-						Str("action", "scaleDown").
-						Msg("Scale down operation completed successfully")
-				}
-			} else {
-				log.Info().
-					Str("component", "scaling").
-					Str("action", "maintain").
-					Msg("No scaling action needed, maintaining current replica count")
-			}
-
-			scaleUpCount = 0
-			scaleDownCount = 0
-			evaluationStart = time.Now()
+				Str("action", "initialize").
+				Str("backend", le.Backend).
+				Err(err).
+				Msg("Failed to create leader election backend")
 		}
-
-		logTimer(config.Get().CheckInterval, cycleCount)
+		elector = leader.NewElector(backend, le.Identity,
+			time.Duration(le.LeaseSeconds)*time.Second, time.Duration(le.RenewSeconds)*time.Second)
+		go elector.Run(ctx)
 	}
-}
 
-func logTimer(duration int, cycle int) {
-	nextCheck := time.Now().Add(time.Duration(duration) * time.Second)
-	log.Debug().
+	grace := time.Duration(config.Get().ShutdownGraceSeconds) * time.Second
+	opCtx, cancelOps := graceContext(ctx, grace)
+	defer cancelOps()
+
+	sup := newSupervisor(source, api, opCtx)
+	sup.reconcile(ctx, config.Get().Targets)
+	config.OnChange(func(_, updated config.Config) {
+		sup.reconcile(ctx, updated.Targets)
+	})
+	go config.Watch(ctx)
+
+	<-ctx.Done()
+	stop()
+	log.Info().
+		Str("component", "app").
+		Str("action", "shutdown").
+		Dur("gracePeriod", grace).
+		Msg("Shutdown signal received, waiting for running operations")
+
+	sup.wait()
+	if elector != nil {
+		// Liberada só após as operações terminarem, para o sucessor não decidir
+		// sobre uma instância ainda em alteração
+		resignCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		if err := elector.Resign(resignCtx); err != nil {
+			log.Error(err).
+				Str("component", "app").
+				Str("action", "shutdown").
+				Msg("Failed to release leadership")
+		}
+		cancel()
+	}
+	if err := state.Flush(); err != nil {
+		log.Error(err).
+			Str("component", "app").
+			Str("action", "shutdown").
+			Msg("Failed to flush state file")
+	}
+	log.Info().
 		Str("component", "app").
-		Str("action", "schedule").
-		Int("cycle", cycle).
-		Str("nextCheckTime", nextCheck.Format("15:04:05")).
-		Int("intervalSeconds", duration).
-		Msg("Next metrics check scheduled")
-	time.Sleep(time.Duration(duration) * time.Second)
+		Str("action", "shutdown").
+		Msg("Shutdown complete")
+	log.Flush()
 }
@@ -0,0 +1,611 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"runtime/debug"
+	"time"
+
+	"github.com/heraque/alloydb-autoscaler/internal/alloydb"
+	"github.com/heraque/alloydb-autoscaler/internal/config"
+	"github.com/heraque/alloydb-autoscaler/internal/health"
+	"github.com/heraque/alloydb-autoscaler/internal/leader"
+	"github.com/heraque/alloydb-autoscaler/internal/log"
+	"github.com/heraque/alloydb-autoscaler/internal/metrics"
+	"github.com/heraque/alloydb-autoscaler/internal/predict"
+	"github.com/heraque/alloydb-autoscaler/internal/resilience"
+	"github.com/heraque/alloydb-autoscaler/internal/scaling"
+	"github.com/heraque/alloydb-autoscaler/internal/telemetry"
+)
+
+// targetRunner executa o ciclo de verificação e decisão de um único alvo,
+// com contadores de votos e agenda próprios. Operações de escala usam opCtx,
+// que sobrevive ao cancelamento do runner pelo período de graça do encerramento.
+type targetRunner struct {
+	name   string
+	source metrics.MetricSource
+	api    alloydb.InstanceAPI
+	opCtx  context.Context
+
+	scaleUpCount    int
+	scaleDownCount  int
+	breach          float64
+	evaluationStart time.Time
+	cycleCount      int
+	standby         bool
+	// paused é o motivo pelo qual a instância ou o cluster não aceita
+	// decisões, vazio quando ambos estão READY
+	paused string
+	// currentReplicas é o número de nós observado na última coleta bem-sucedida
+	currentReplicas int
+	// desired é o maior número de nós calculado pelo target tracking na
+	// janela de avaliação, 0 na política step
+	desired int
+	// schedule é o nome do agendamento ativo, vazio quando nenhum está ativo
+	schedule string
+	// predictor existe enquanto a escala preditiva estiver ativa no alvo;
+	// forecast é o número de nós previsto, 0 sem previsão
+	predictor *predict.Predictor
+	forecast  int
+	// dryRunLimits identifica os limites cuja aplicação já foi registrada em
+	// modo dry-run, vazio quando o read pool está dentro dos limites
+	dryRunLimits string
+}
+
+func newTargetRunner(name string, source metrics.MetricSource, api alloydb.InstanceAPI, opCtx context.Context) *targetRunner {
+	return &targetRunner{
+		name:            name,
+		source:          source,
+		api:             api,
+		opCtx:           opCtx,
+		evaluationStart: time.Now(),
+	}
+}
+
+// run executa ciclos até o contexto ser cancelado ou o alvo deixar de existir
+func (r *targetRunner) run(ctx context.Context) {
+	if target, ok := config.Get().Target(r.name); ok {
+		r.resume(target)
+	}
+
+	for {
+		target, ok := config.Get().Target(r.name)
+		if !ok {
+			log.Info().
+				Str("component", "app").
+				Str("action", "stop").
+				Str("target", r.name).
+				Msg("Target no longer configured, stopping")
+			return
+		}
+
+		r.safeCycle(ctx, target)
+		health.CycleCompleted(r.name)
+
+		if !r.wait(ctx, target) {
+			return
+		}
+	}
+}
+
+// resume aguarda operações deixadas pendentes por um processo anterior, para
+// que o primeiro ciclo decida sobre o número de nós já atualizado
+func (r *targetRunner) resume(target config.Target) {
+	health.OperationStarted(r.name)
+	err := scaling.ResumePending(r.opCtx, r.api, target)
+	health.OperationFinished(r.name)
+	if err != nil && r.opCtx.Err() == nil {
+		log.Error(err).
+			Str("component", "scaling").
+			Str("action", "resume").
+			Str("target", r.name).
+			Msg("Failed to resume pending operation")
+	}
+}
+
+// safeCycle isola falhas inesperadas de um alvo para não interromper os demais
+func (r *targetRunner) safeCycle(ctx context.Context, target config.Target) {
+	defer func() {
+		if rec := recover(); rec != nil {
+			log.ErrorMessage(fmt.Sprintf("%v", rec)).
+				Str("component", "app").
+				Str("action", "check").
+				Str("target", r.name).
+				Int("cycle", r.cycleCount).
+				Str("stack", string(debug.Stack())).
+				Msg("Recovered from panic in target cycle")
+		}
+	}()
+	r.cycle(ctx, target)
+}
+
+func (r *targetRunner) cycle(baseCtx context.Context, target config.Target) {
+	r.cycleCount++
+	cycleStartTime := time.Now()
+
+	if !r.checkLeadership() {
+		return
+	}
+	target = r.applySchedule(target)
+
+	// Uma única leitura da instância por ciclo: a verificação das métricas, a
+	// previsão e o PATCH partem do mesmo estado
+	snapshot, err := alloydb.GetSnapshot(baseCtx, r.api, target)
+	if err != nil {
+		if baseCtx.Err() != nil {
+			return
+		}
+		health.MetricsCollected(r.name, err)
+		log.Error(err).
+			Str("component", "app").
+			Str("action", "check").
+			Str("target", r.name).
+			Str("errorClass", string(resilience.Classify(err))).
+			Int("cycle", r.cycleCount).
+			Msg("Error reading instance")
+		return
+	}
+	if !r.checkState(snapshot) {
+		return
+	}
+	target = r.applyForecast(baseCtx, snapshot, target)
+
+	func() {
+		ctx, cancel := context.WithTimeout(baseCtx, time.Duration(config.Get().TimeoutSeconds)*time.Second)
+		defer cancel()
+
+		log.Debug().
+			Str("component", "app").
+			Str("action", "check").
+			Str("target", r.name).
+			Int("cycle", r.cycleCount).
+			Msg("Starting metrics check cycle")
+
+		result, err := metrics.CheckMetrics(ctx, r.source, snapshot, target, r.scaleUpCount, r.scaleDownCount)
+		if baseCtx.Err() != nil {
+			// Encerramento em andamento: a coleta foi interrompida, não é uma falha
+			return
+		}
+		health.MetricsCollected(r.name, err)
+		if err != nil {
+			if ctx.Err() == context.DeadlineExceeded {
+				log.ErrorMessage("Metrics check timeout").
+					Str("component", "app").
+					Str("action", "check").
+					Str("target", r.name).
+					Int("timeoutSeconds", config.Get().TimeoutSeconds).
+					Int("cycle", r.cycleCount).
+					Send()
+			} else {
+				log.Error(err).
+					Str("component", "app").
+					Str("action", "check").
+					Str("target", r.name).
+					Str("errorClass", string(resilience.Classify(err))).
+					Int("cycle", r.cycleCount).
+					Msg("Error checking metrics")
+			}
+		} else {
+			r.scaleUpCount = result.ScaleUpVotes
+			r.scaleDownCount = result.ScaleDownVotes
+			r.breach = result.Breach
+			r.currentReplicas = result.CurrentReplicas
+			r.desired = max(r.desired, result.DesiredReplicas)
+		}
+		telemetry.SetVotes(r.name, r.scaleUpCount, r.scaleDownCount)
+
+		log.Debug().
+			Str("component", "app").
+			Str("action", "check").
+			Str("target", r.name).
+			Int("cycle", r.cycleCount).
+			Str("duration", fmt.Sprintf("%.2fs", time.Since(cycleStartTime).Seconds())).
+			Msg("Metrics check cycle completed")
+	}()
+
+	// A liderança pode ter sido perdida durante a coleta
+	if baseCtx.Err() != nil || !leader.IsLeader() {
+		return
+	}
+
+	if r.enforceLimits(&snapshot, target) {
+		r.resetVotes()
+		return
+	}
+
+	evalElapsed := time.Since(r.evaluationStart)
+	if evalElapsed < time.Duration(target.Evaluation)*time.Second {
+		return
+	}
+
+	log.Info().
+		Str("component", "scaling").
+		Str("action", "decision").
+		Str("target", r.name).
+		Int("scaleUpVotes", r.scaleUpCount).
+		Int("scaleDownVotes", r.scaleDownCount).
+		Str("breach", fmt.Sprintf("%.1f%%", r.breach)).
+		Str("policy", target.ScalingPolicy).
+		Int("currentReplicas", r.currentReplicas).
+		Int("desiredReplicas", r.desired).
+		Str("evaluationPeriod", fmt.Sprintf("%.2fs", evalElapsed.Seconds())).
+		Str("schedule", r.scheduleName()).
+		Int("forecastReplicas", r.forecast).
+		Int("minReplicas", target.MinReplicas).
+		Int("maxReplicas", target.MaxReplicas).
+		Msg("Making scaling decision")
+
+	reason := fmt.Sprintf("scaleUpVotes=%d scaleDownVotes=%d evaluationPeriod=%.0fs breach=%.1f%% schedule=%s forecastReplicas=%d", r.scaleUpCount, r.scaleDownCount, evalElapsed.Seconds(), r.breach, r.scheduleName(), r.forecast)
+	scaleUp := r.scaleUpCount > r.scaleDownCount && r.scaleUpCount > 0
+	scaleDown := r.scaleDownCount > r.scaleUpCount && r.scaleDownCount > 0
+	if target.ScalingPolicy == config.PolicyTargetTracking {
+		reason = fmt.Sprintf("policy=%s currentReplicas=%d desiredReplicas=%d evaluationPeriod=%.0fs schedule=%s forecastReplicas=%d", target.ScalingPolicy, r.currentReplicas, r.desired, evalElapsed.Seconds(), r.scheduleName(), r.forecast)
+		scaleUp = r.desired > r.currentReplicas
+		scaleDown = r.desired > 0 && r.desired < r.currentReplicas
+	}
+	if scaleUp && r.inCooldown(target, "scaleUp") || scaleDown && r.inCooldown(target, "scaleDown") {
+		scaleUp, scaleDown = false, false
+	}
+
+	if scaleUp {
+		telemetry.RecordDecision(r.name, telemetry.DecisionScaleUp)
+		health.OperationStarted(r.name)
+		err := r.scale(snapshot, target, scaling.ScaleUp, reason)
+		health.OperationFinished(r.name)
+		if err != nil {
+			log.Error(err).
+				Str("component", "scaling").
+				Str("action", "scaleUp").
+				Str("target", r.name).
+				Str("errorClass", string(resilience.Classify(err))).
+				Msg("Failed to scale up replicas")
+		} else {
+			log.Info().
+				Str("component", "scaling").
+				Str("action", "scaleUp").
+				Str("target", r.name).
+				Bool("dryRun", target.DryRun).
+				Msg("Scale up operation completed successfully")
+		}
+	} else if scaleDown {
+		telemetry.RecordDecision(r.name, telemetry.DecisionScaleDown)
+		health.OperationStarted(r.name)
+		err := r.scale(snapshot, target, scaling.ScaleDown, reason)
+		health.OperationFinished(r.name)
+		if err != nil {
+			log.Error(err).
+				Str("component", "scaling").
+				Str("action", "scaleDown").
+				Str("target", r.name).
+				Str("errorClass", string(resilience.Classify(err))).
+				Msg("Failed to scale down replicas")
+		} else {
+			log.Info().
+				Str("component", "scaling").
+				Str("action", "scaleDown").
+				Str("target", r.name).
+				Bool("dryRun", target.DryRun).
+				Msg("Scale down operation completed successfully")
+		}
+	} else {
+		telemetry.RecordDecision(r.name, telemetry.DecisionMaintain)
+		log.Info().
+			Str("component", "scaling").
+			Str("action", "maintain").
+			Str("target", r.name).
+			Msg("No scaling action needed, maintaining current replica count")
+	}
+
+	r.resetVotes()
+}
+
+// scale aplica a decisão: na política step, step é ScaleUp ou ScaleDown e
+// usa o passo configurado; no target tracking, o read pool vai direto ao
+// número de nós desejado em uma única alteração
+func (r *targetRunner) scale(snapshot alloydb.InstanceSnapshot, target config.Target, step func(context.Context, alloydb.InstanceAPI, alloydb.InstanceSnapshot, config.Target, float64, string) error, reason string) error {
+	up := r.desired > snapshot.NodeCount
+	return r.withConflictRetry(snapshot, target, func(snapshot alloydb.InstanceSnapshot) error {
+		if target.ScalingPolicy == config.PolicyTargetTracking {
+			// Outro agente pode já ter levado o read pool além do desejado
+			if up && snapshot.NodeCount >= r.desired || !up && snapshot.NodeCount <= r.desired {
+				return nil
+			}
+			return scaling.ScaleTo(r.opCtx, r.api, snapshot, target, r.desired, reason)
+		}
+		return step(r.opCtx, r.api, snapshot, target, r.breach, reason)
+	})
+}
+
+// withConflictRetry executa apply com o snapshot do ciclo. Se a instância foi
+// alterada por outro agente depois da leitura (etag desatualizado), relê a
+// instância e executa apply mais uma vez, de modo que a decisão seja refeita
+// a partir do número de nós atual. Um segundo conflito é devolvido como erro.
+func (r *targetRunner) withConflictRetry(snapshot alloydb.InstanceSnapshot, target config.Target, apply func(alloydb.InstanceSnapshot) error) error {
+	err := apply(snapshot)
+	if !errors.Is(err, alloydb.ErrConflict) {
+		return err
+	}
+
+	fresh, readErr := alloydb.GetSnapshot(r.opCtx, r.api, target)
+	if readErr != nil {
+		return fmt.Errorf("%w; re-reading instance: %w", err, readErr)
+	}
+	if reason := fresh.NotReady(); reason != "" {
+		// O próximo ciclo pausa as decisões até a instância voltar a READY
+		return fmt.Errorf("%w; not deciding again: %s", err, reason)
+	}
+	log.Warn().
+		Str("component", "scaling").
+		Str("action", "conflict").
+		Str("target", r.name).
+		Str("etag", snapshot.Etag).
+		Str("currentEtag", fresh.Etag).
+		Int("readReplicas", snapshot.NodeCount).
+		Int("currentReplicas", fresh.NodeCount).
+		Msg("Instance changed since it was read, deciding again from the current state")
+	r.currentReplicas = fresh.NodeCount
+	return apply(fresh)
+}
+
+// applySchedule aplica aos limites do alvo o agendamento ativo, registrando
+// quando um agendamento começa ou termina
+func (r *targetRunner) applySchedule(target config.Target) config.Target {
+	effective, schedule := target.WithSchedule(time.Now())
+	name := ""
+	if schedule != nil {
+		name = schedule.Name
+	}
+	if name == r.schedule {
+		return effective
+	}
+
+	if name != "" {
+		log.Info().
+			Str("component", "scaling").
+			Str("action", "schedule").
+			Str("target", r.name).
+			Str("schedule", name).
+			Str("start", schedule.Start).
+			Str("duration", schedule.Duration.String()).
+			Int("minReplicas", effective.MinReplicas).
+			Int("maxReplicas", effective.MaxReplicas).
+			Msg("Schedule active, overriding replica limits")
+	} else {
+		log.Info().
+			Str("component", "scaling").
+			Str("action", "schedule").
+			Str("target", r.name).
+			Str("schedule", r.schedule).
+			Int("minReplicas", effective.MinReplicas).
+			Int("maxReplicas", effective.MaxReplicas).
+			Msg("Schedule ended, restoring target replica limits")
+	}
+	r.schedule = name
+	return effective
+}
+
+// applyForecast eleva o mínimo de réplicas do alvo ao número de nós previsto
+// pela escala preditiva. As regras reativas continuam podendo aumentar acima
+// da previsão, mas não reduzir abaixo dela.
+func (r *targetRunner) applyForecast(ctx context.Context, snapshot alloydb.InstanceSnapshot, target config.Target) config.Target {
+	if !target.Predictive.Enabled {
+		r.predictor = nil
+		r.forecast = 0
+		return target
+	}
+	if r.predictor == nil {
+		r.predictor = predict.NewPredictor(r.source)
+	}
+
+	now := time.Now()
+	if err := r.predictor.Refresh(ctx, snapshot, target, now); err != nil && ctx.Err() == nil {
+		log.Error(err).
+			Str("component", "predict").
+			Str("action", "refresh").
+			Str("target", r.name).
+			Msg("Failed to rebuild seasonal profiles, keeping the previous forecast")
+	}
+
+	forecast, ok := r.predictor.Forecast(target, now)
+	if !ok {
+		r.forecast = 0
+		return target
+	}
+	telemetry.SetForecastReplicas(r.name, forecast.Replicas)
+	replicas := min(forecast.Replicas, target.MaxReplicas)
+	if replicas != r.forecast {
+		log.Info().
+			Str("component", "predict").
+			Str("action", "forecast").
+			Str("target", r.name).
+			Int("forecastReplicas", replicas).
+			Str("rule", forecast.Rule).
+			Float64("load", math.Round(forecast.Load*100)/100).
+			Time("at", forecast.At).
+			Int("maxReplicas", target.MaxReplicas).
+			Msg("Forecast replica count changed")
+	}
+	r.forecast = replicas
+	target.MinReplicas = max(target.MinReplicas, replicas)
+	return target
+}
+
+// enforceLimits leva o read pool para dentro dos limites efetivos do alvo,
+// que incluem o agendamento ativo e a previsão, sem esperar a janela de
+// avaliação nem o cooldown. Assim, o fim de um agendamento ou da previsão
+// também traz o read pool de volta aos limites do próprio alvo. Retorna true
+// quando uma ação foi tomada.
+//
+// Em modo dry-run a instância não muda: a decisão é registrada uma vez para
+// cada conjunto de limites, e nos ciclos seguintes snapshot passa a ter o
+// número de nós virtual, já dentro dos limites, para que a avaliação reativa
+// continue a partir dele.
+func (r *targetRunner) enforceLimits(snapshot *alloydb.InstanceSnapshot, target config.Target) bool {
+	limit := func(current int) int {
+		return min(max(current, target.MinReplicas), target.MaxReplicas)
+	}
+	count := limit(snapshot.NodeCount)
+	if count == snapshot.NodeCount {
+		r.dryRunLimits = ""
+		return false
+	}
+	reason := fmt.Sprintf("schedule=%s forecastReplicas=%d minReplicas=%d maxReplicas=%d", r.scheduleName(), r.forecast, target.MinReplicas, target.MaxReplicas)
+
+	if target.DryRun && reason == r.dryRunLimits {
+		snapshot.NodeCount = count
+		r.currentReplicas = count
+		return false
+	}
+
+	decision := telemetry.DecisionScaleUp
+	if count < snapshot.NodeCount {
+		decision = telemetry.DecisionScaleDown
+	}
+	telemetry.RecordDecision(r.name, decision)
+	if target.DryRun {
+		r.dryRunLimits = reason
+	}
+
+	health.OperationStarted(r.name)
+	err := r.withConflictRetry(*snapshot, target, func(snapshot alloydb.InstanceSnapshot) error {
+		return scaling.ScaleTo(r.opCtx, r.api, snapshot, target, limit(snapshot.NodeCount), reason)
+	})
+	health.OperationFinished(r.name)
+	if err != nil {
+		log.Error(err).
+			Str("component", "scaling").
+			Str("action", "limits").
+			Str("target", r.name).
+			Str("errorClass", string(resilience.Classify(err))).
+			Str("schedule", r.scheduleName()).
+			Int("forecastReplicas", r.forecast).
+			Msg("Failed to apply scheduled or forecast replica limits")
+	}
+	return true
+}
+
+// scheduleName devolve o agendamento ativo para os logs de decisão
+func (r *targetRunner) scheduleName() string {
+	if r.schedule == "" {
+		return "none"
+	}
+	return r.schedule
+}
+
+// inCooldown informa se action está suprimida pelo cooldown da última
+// operação de escala, registrando o tempo restante
+func (r *targetRunner) inCooldown(target config.Target, action string) bool {
+	cooldown, active := scaling.ActiveCooldown(target, action)
+	if !active {
+		return false
+	}
+	log.Info().
+		Str("component", "scaling").
+		Str("action", "cooldown").
+		Str("target", r.name).
+		Str("suppressedAction", action).
+		Str("lastAction", cooldown.LastAction).
+		Time("lastCompletedAt", cooldown.CompletedAt).
+		Str("remaining", fmt.Sprintf("%.0fs", cooldown.Remaining.Seconds())).
+		Str("scope", target.CooldownScope).
+		Msg("Scaling action suppressed by cooldown")
+	return true
+}
+
+// checkLeadership descarta os votos enquanto o processo estiver em standby e
+// reinicia a janela de avaliação ao assumir a liderança, para que a primeira
+// decisão use uma janela completa de votos próprios
+func (r *targetRunner) checkLeadership() bool {
+	if !leader.IsLeader() {
+		if !r.standby {
+			log.Debug().
+				Str("component", "app").
+				Str("action", "standby").
+				Str("target", r.name).
+				Msg("Not the leader, skipping evaluation")
+		}
+		r.standby = true
+		r.resetVotes()
+		return false
+	}
+
+	if r.standby {
+		r.standby = false
+		r.resetVotes()
+		log.Debug().
+			Str("component", "app").
+			Str("action", "lead").
+			Str("target", r.name).
+			Msg("Leader, starting a new evaluation window")
+	}
+	return true
+}
+
+// checkState suspende votos e decisões enquanto a instância ou o cluster não
+// estiver READY ou uma operação estiver em andamento, como em manutenções,
+// failovers ou alterações feitas fora do autoscaler. Ao retomar, inicia uma
+// nova janela de avaliação, pois os votos anteriores não refletem o estado atual.
+func (r *targetRunner) checkState(snapshot alloydb.InstanceSnapshot) bool {
+	reason := snapshot.NotReady()
+	if reason != "" {
+		if reason != r.paused {
+			log.Warn().
+				Str("component", "app").
+				Str("action", "pause").
+				Str("target", r.name).
+				Str("reason", reason).
+				Str("state", snapshot.State).
+				Str("clusterState", snapshot.ClusterState).
+				Msg("Instance not ready, pausing scaling decisions")
+		}
+		r.paused = reason
+		health.Paused(r.name, reason)
+		r.resetVotes()
+		return false
+	}
+
+	if r.paused != "" {
+		log.Info().
+			Str("component", "app").
+			Str("action", "resume").
+			Str("target", r.name).
+			Str("pausedBy", r.paused).
+			Msg("Instance ready, resuming with a new evaluation window")
+		r.paused = ""
+		health.Paused(r.name, "")
+		r.resetVotes()
+	}
+	return true
+}
+
+func (r *targetRunner) resetVotes() {
+	r.scaleUpCount = 0
+	r.scaleDownCount = 0
+	r.breach = 0
+	r.desired = 0
+	r.evaluationStart = time.Now()
+	telemetry.SetVotes(r.name, 0, 0)
+}
+
+// wait aguarda o intervalo de verificação do alvo; retorna false se o contexto for cancelado
+func (r *targetRunner) wait(ctx context.Context, target config.Target) bool {
+	duration := target.CheckInterval
+	nextCheck := time.Now().Add(time.Duration(duration) * time.Second)
+	log.Debug().
+		Str("component", "app").
+		Str("action", "schedule").
+		Str("target", r.name).
+		Int("cycle", r.cycleCount).
+		Str("nextCheckTime", nextCheck.Format("15:04:05")).
+		Int("intervalSeconds", duration).
+		Msg("Next metrics check scheduled")
+
+	select {
+	case <-ctx.Done():
+		return false
+	case <-time.After(time.Duration(duration) * time.Second):
+		return true
+	}
+}
@@ -0,0 +1,422 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"os"
+	"slices"
+	"testing"
+
+	"github.com/heraque/alloydb-autoscaler/internal/alloydb"
+	"github.com/heraque/alloydb-autoscaler/internal/config"
+	"github.com/heraque/alloydb-autoscaler/internal/metrics"
+	"github.com/heraque/alloydb-autoscaler/internal/scaling"
+	alloydbapi "google.golang.org/api/alloydb/v1"
+	"google.golang.org/api/googleapi"
+)
+
+// loadMetric é a métrica da única regra dos alvos de teste: acima de 70 vota
+// para aumentar e abaixo de 30 para reduzir
+const loadMetric = "custom.googleapis.com/load"
+
+func TestMain(m *testing.M) {
+	alloydb.OperationPollInterval = 0
+	config.Set(config.Config{TimeoutSeconds: 10, OperationMaxWaitSeconds: 60})
+	os.Exit(m.Run())
+}
+
+// newTestTarget cria um alvo de 1 a 5 nós que decide a cada ciclo
+func newTestTarget(t *testing.T) config.Target {
+	t.Helper()
+	t.Cleanup(func() { scaling.Forget(t.Name()) })
+	return config.Target{
+		Name:          t.Name(),
+		GCPProject:    "project",
+		Region:        "region",
+		ClusterName:   "cluster",
+		InstanceName:  "read-pool",
+		CheckInterval: 1,
+		MinReplicas:   1,
+		MaxReplicas:   5,
+		ScalingPolicy: config.PolicyStep,
+		CooldownScope: config.CooldownOpposite,
+		Rules: []config.Rule{{
+			Name:               "load",
+			Metric:             loadMetric,
+			Conversion:         config.ConversionNone,
+			NodeAggregation:    config.AggregationMax,
+			Scale:              1,
+			Threshold:          70,
+			ScaleDownThreshold: 30,
+			Direction:          config.DirectionBoth,
+			Weight:             1,
+		}},
+	}
+}
+
+// newTestRunner registra a instância do alvo com nodes nós e devolve um
+// runner cuja métrica de carga vale load
+func newTestRunner(t *testing.T, target config.Target, nodes int, load float64) (*targetRunner, *alloydb.Fake, *metrics.FakeSource) {
+	t.Helper()
+	api := alloydb.NewFake()
+	if err := api.AddInstance(target.InstancePath(), nodes, 2); err != nil {
+		t.Fatal(err)
+	}
+	source := metrics.NewFakeSource()
+	source.SetSeries(loadMetric, metrics.DoubleSeries(nil, load))
+	return newTargetRunner(target.Name, source, api, context.Background()), api, source
+}
+
+func TestCycle(t *testing.T) {
+	tests := []struct {
+		name      string
+		nodes     int
+		load      float64
+		want      int
+		wantPatch bool
+	}{
+		{name: "scale up above threshold", nodes: 2, load: 90, want: 3, wantPatch: true},
+		{name: "scale down below scale-down threshold", nodes: 3, load: 10, want: 2, wantPatch: true},
+		{name: "maintain inside hysteresis band", nodes: 3, load: 50, want: 3},
+		{name: "maintain at max", nodes: 5, load: 90, want: 5},
+		{name: "maintain at min", nodes: 1, load: 10, want: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			target := newTestTarget(t)
+			r, api, _ := newTestRunner(t, target, tt.nodes, tt.load)
+
+			r.cycle(context.Background(), target)
+
+			if got := api.NodeCount(target.InstancePath()); got != tt.want {
+				t.Errorf("node count = %d, want %d", got, tt.want)
+			}
+			if got := len(api.Patches()) > 0; got != tt.wantPatch {
+				t.Errorf("patched = %v, want %v", got, tt.wantPatch)
+			}
+			if r.scaleUpCount != 0 || r.scaleDownCount != 0 {
+				t.Errorf("votes after decision = %d/%d, want reset", r.scaleUpCount, r.scaleDownCount)
+			}
+		})
+	}
+}
+
+func TestCycleAccumulatesVotesUntilEvaluation(t *testing.T) {
+	target := newTestTarget(t)
+	target.Evaluation = 3600
+	r, api, _ := newTestRunner(t, target, 2, 90)
+
+	for range 3 {
+		r.cycle(context.Background(), target)
+	}
+
+	if r.scaleUpCount != 3 {
+		t.Errorf("scaleUpCount = %d, want 3", r.scaleUpCount)
+	}
+	if patches := api.Patches(); len(patches) != 0 {
+		t.Errorf("patches = %+v, want none before the evaluation window ends", patches)
+	}
+}
+
+func TestCycleMetricsError(t *testing.T) {
+	target := newTestTarget(t)
+	r, api, source := newTestRunner(t, target, 2, 90)
+	source.EnqueueError(loadMetric, errors.New("monitoring unavailable"))
+
+	r.cycle(context.Background(), target)
+
+	if patches := api.Patches(); len(patches) != 0 {
+		t.Errorf("patches = %+v, want none after a metrics error", patches)
+	}
+}
+
+func TestCycleNotReady(t *testing.T) {
+	target := newTestTarget(t)
+	r, api, source := newTestRunner(t, target, 2, 90)
+	api.SetCluster(&alloydbapi.Cluster{Name: target.ClusterPath(), State: "MAINTENANCE"})
+
+	r.cycle(context.Background(), target)
+
+	if r.paused == "" {
+		t.Error("runner not paused while the cluster is in maintenance")
+	}
+	if requests := source.Requests(); len(requests) != 0 {
+		t.Errorf("metrics queried %d times while paused", len(requests))
+	}
+	if patches := api.Patches(); len(patches) != 0 {
+		t.Errorf("patches = %+v, want none while paused", patches)
+	}
+
+	api.SetCluster(&alloydbapi.Cluster{Name: target.ClusterPath(), State: "READY"})
+	r.cycle(context.Background(), target)
+	if r.paused != "" {
+		t.Errorf("paused = %q after the cluster is ready", r.paused)
+	}
+	if got := api.NodeCount(target.InstancePath()); got != 3 {
+		t.Errorf("node count = %d after resuming, want 3", got)
+	}
+}
+
+func TestCycleConflictDecidesAgain(t *testing.T) {
+	target := newTestTarget(t)
+	r, api, _ := newTestRunner(t, target, 2, 90)
+	api.FailNext(alloydb.MethodPatchInstance, &googleapi.Error{Code: http.StatusConflict, Message: "etag mismatch"})
+
+	r.cycle(context.Background(), target)
+
+	if got := api.NodeCount(target.InstancePath()); got != 3 {
+		t.Errorf("node count = %d, want 3 after retrying the conflict", got)
+	}
+	if got := api.Calls(alloydb.MethodGetInstance); got != 2 {
+		t.Errorf("GetInstance calls = %d, want 2 (cycle and re-read after the conflict)", got)
+	}
+}
+
+func TestCycleEnforcesLimits(t *testing.T) {
+	tests := []struct {
+		name     string
+		schedule string
+		min, max int
+		nodes    int
+		load     float64
+		want     int
+	}{
+		// O agendamento terminou com o read pool acima do máximo do alvo, e a
+		// carga está na faixa de histerese
+		{name: "above max after a schedule ends", schedule: "peak", min: 1, max: 3, nodes: 5, load: 50, want: 3},
+		{name: "above max even under load", min: 1, max: 3, nodes: 5, load: 90, want: 3},
+		{name: "below min", min: 3, max: 5, nodes: 1, load: 10, want: 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			target := newTestTarget(t)
+			target.MinReplicas, target.MaxReplicas = tt.min, tt.max
+			r, api, _ := newTestRunner(t, target, tt.nodes, tt.load)
+			r.schedule = tt.schedule
+
+			r.cycle(context.Background(), target)
+
+			if got := api.NodeCount(target.InstancePath()); got != tt.want {
+				t.Errorf("node count = %d, want %d", got, tt.want)
+			}
+			if patches := api.Patches(); len(patches) != 1 {
+				t.Errorf("patches = %d, want a single PATCH to the limit", len(patches))
+			}
+			if r.schedule != "" {
+				t.Errorf("schedule = %q after the window ended", r.schedule)
+			}
+		})
+	}
+}
+
+func TestCycleEnforcesLimitsDryRun(t *testing.T) {
+	target := newTestTarget(t)
+	target.DryRun = true
+	target.MaxReplicas = 3
+	r, api, _ := newTestRunner(t, target, 5, 10)
+
+	for range 3 {
+		r.cycle(context.Background(), target)
+	}
+
+	if patches := api.Patches(); len(patches) != 0 {
+		t.Errorf("patches = %+v, want none in dry run", patches)
+	}
+	records := scaling.DryRunRecords(target.Name)
+	// O limite é registrado uma vez; nos ciclos seguintes a avaliação reativa
+	// parte dos 3 nós virtuais
+	want := []struct{ current, target int }{{5, 3}, {3, 2}, {3, 2}}
+	if len(records) != len(want) {
+		t.Fatalf("dry run records = %+v, want %d", records, len(want))
+	}
+	for i, w := range want {
+		if records[i].CurrentCount != w.current || records[i].TargetCount != w.target {
+			t.Errorf("record %d = %d -> %d, want %d -> %d", i, records[i].CurrentCount, records[i].TargetCount, w.current, w.target)
+		}
+	}
+}
+
+// modifyInstance altera a instância no Fake como um agente externo, o que
+// renova o etag
+func modifyInstance(t *testing.T, api *alloydb.Fake, name string, modify func(*alloydbapi.Instance)) {
+	t.Helper()
+	instance, err := api.Instance(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	modify(instance)
+	if err := api.SetInstance(instance); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestWithConflictRetry(t *testing.T) {
+	resize := func(nodes int64) func(*alloydbapi.Instance) {
+		return func(instance *alloydbapi.Instance) { instance.ReadPoolConfig.NodeCount = nodes }
+	}
+	tests := []struct {
+		name string
+		// changes[i] altera a instância antes da tentativa i; a primeira
+		// tentativa usa o snapshot lido antes da alteração
+		changes      map[int]func(*alloydbapi.Instance)
+		wantAttempts []int
+		wantNodes    int
+		wantConflict bool
+		wantErr      bool
+	}{
+		{
+			name:         "no conflict",
+			wantAttempts: []int{2},
+			wantNodes:    3,
+		},
+		{
+			name:         "decides again from the current node count",
+			changes:      map[int]func(*alloydbapi.Instance){0: resize(4)},
+			wantAttempts: []int{2, 4},
+			wantNodes:    5,
+		},
+		{
+			name: "instance not ready after the conflict",
+			changes: map[int]func(*alloydbapi.Instance){0: func(instance *alloydbapi.Instance) {
+				instance.ReadPoolConfig.NodeCount = 4
+				instance.State = "MAINTENANCE"
+			}},
+			wantAttempts: []int{2},
+			wantNodes:    4,
+			wantConflict: true,
+			wantErr:      true,
+		},
+		{
+			name:         "second conflict",
+			changes:      map[int]func(*alloydbapi.Instance){0: resize(4), 1: resize(1)},
+			wantAttempts: []int{2, 4},
+			wantNodes:    1,
+			wantConflict: true,
+			wantErr:      true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			target := newTestTarget(t)
+			r, api, _ := newTestRunner(t, target, 2, 50)
+			snapshot, err := alloydb.GetSnapshot(context.Background(), api, target)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var attempts []int
+			err = r.withConflictRetry(snapshot, target, func(snapshot alloydb.InstanceSnapshot) error {
+				if change, ok := tt.changes[len(attempts)]; ok {
+					modifyInstance(t, api, target.InstancePath(), change)
+				}
+				attempts = append(attempts, snapshot.NodeCount)
+				return scaling.ScaleUp(context.Background(), api, snapshot, target, 0, "test")
+			})
+
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("withConflictRetry() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got := errors.Is(err, alloydb.ErrConflict); got != tt.wantConflict {
+				t.Errorf("errors.Is(err, ErrConflict) = %v, want %v (err = %v)", got, tt.wantConflict, err)
+			}
+			if !slices.Equal(attempts, tt.wantAttempts) {
+				t.Errorf("attempts from node counts %v, want %v", attempts, tt.wantAttempts)
+			}
+			if got := api.NodeCount(target.InstancePath()); got != tt.wantNodes {
+				t.Errorf("node count = %d, want %d", got, tt.wantNodes)
+			}
+		})
+	}
+}
+
+func TestCyclePausesWhileNotReady(t *testing.T) {
+	tests := []struct {
+		name    string
+		pause   func(t *testing.T, api *alloydb.Fake, target config.Target)
+		resume  func(t *testing.T, api *alloydb.Fake, target config.Target)
+		wantWhy string
+	}{
+		{
+			name: "instance in maintenance",
+			pause: func(t *testing.T, api *alloydb.Fake, target config.Target) {
+				modifyInstance(t, api, target.InstancePath(), func(instance *alloydbapi.Instance) { instance.State = "MAINTENANCE" })
+			},
+			resume: func(t *testing.T, api *alloydb.Fake, target config.Target) {
+				modifyInstance(t, api, target.InstancePath(), func(instance *alloydbapi.Instance) { instance.State = "READY" })
+			},
+			wantWhy: "instance state is MAINTENANCE",
+		},
+		{
+			name: "operation running on the instance",
+			pause: func(t *testing.T, api *alloydb.Fake, target config.Target) {
+				modifyInstance(t, api, target.InstancePath(), func(instance *alloydbapi.Instance) { instance.Reconciling = true })
+			},
+			resume: func(t *testing.T, api *alloydb.Fake, target config.Target) {
+				modifyInstance(t, api, target.InstancePath(), func(instance *alloydbapi.Instance) { instance.Reconciling = false })
+			},
+			wantWhy: "an operation is running on the instance",
+		},
+		{
+			name: "cluster in maintenance",
+			pause: func(_ *testing.T, api *alloydb.Fake, target config.Target) {
+				api.SetCluster(&alloydbapi.Cluster{Name: target.ClusterPath(), State: "MAINTENANCE"})
+			},
+			resume: func(_ *testing.T, api *alloydb.Fake, target config.Target) {
+				api.SetCluster(&alloydbapi.Cluster{Name: target.ClusterPath(), State: "READY"})
+			},
+			wantWhy: "cluster state is MAINTENANCE",
+		},
+		{
+			name: "operation running on the cluster",
+			pause: func(_ *testing.T, api *alloydb.Fake, target config.Target) {
+				api.SetCluster(&alloydbapi.Cluster{Name: target.ClusterPath(), State: "READY", Reconciling: true})
+			},
+			resume: func(_ *testing.T, api *alloydb.Fake, target config.Target) {
+				api.SetCluster(&alloydbapi.Cluster{Name: target.ClusterPath(), State: "READY"})
+			},
+			wantWhy: "an operation is running on the cluster",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			target := newTestTarget(t)
+			target.Evaluation = 3600
+			r, api, _ := newTestRunner(t, target, 2, 90)
+			ctx := context.Background()
+
+			r.cycle(ctx, target)
+			r.cycle(ctx, target)
+			if r.scaleUpCount != 2 {
+				t.Fatalf("scaleUpCount = %d before pausing, want 2", r.scaleUpCount)
+			}
+
+			tt.pause(t, api, target)
+			// Com a janela de avaliação encerrada, só a pausa impede a decisão
+			target.Evaluation = 0
+			r.cycle(ctx, target)
+			if r.paused != tt.wantWhy {
+				t.Errorf("paused = %q, want %q", r.paused, tt.wantWhy)
+			}
+			if r.scaleUpCount != 0 || r.scaleDownCount != 0 {
+				t.Errorf("votes while paused = %d/%d, want 0/0", r.scaleUpCount, r.scaleDownCount)
+			}
+			if patches := api.Patches(); len(patches) != 0 {
+				t.Errorf("patches = %+v, want none while paused", patches)
+			}
+
+			tt.resume(t, api, target)
+			target.Evaluation = 3600
+			r.cycle(ctx, target)
+			if r.paused != "" {
+				t.Errorf("paused = %q after resuming", r.paused)
+			}
+			if r.scaleUpCount != 1 {
+				t.Errorf("scaleUpCount = %d after resuming, want 1 from a new evaluation window", r.scaleUpCount)
+			}
+			if patches := api.Patches(); len(patches) != 0 {
+				t.Errorf("patches = %+v, want none before the new evaluation window ends", patches)
+			}
+		})
+	}
+}
@@ -0,0 +1,26 @@
+package main
+
+import (
+	"context"
+	"time"
+)
+
+// graceContext retorna um contexto para operações de escala que não é
+// cancelado junto com ctx: ao receber o sinal de encerramento, as operações em
+// andamento ainda têm grace para terminar antes do cancelamento.
+func graceContext(ctx context.Context, grace time.Duration) (context.Context, context.CancelFunc) {
+	opCtx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+	stop := context.AfterFunc(ctx, func() {
+		timer := time.NewTimer(grace)
+		defer timer.Stop()
+		select {
+		case <-timer.C:
+			cancel()
+		case <-opCtx.Done():
+		}
+	})
+	return opCtx, func() {
+		stop()
+		cancel()
+	}
+}
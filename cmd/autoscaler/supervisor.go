@@ -0,0 +1,144 @@
+package main
+
+import (
+	"context"
+	"sync"
+
+	"github.com/heraque/alloydb-autoscaler/internal/alloydb"
+	"github.com/heraque/alloydb-autoscaler/internal/config"
+	"github.com/heraque/alloydb-autoscaler/internal/health"
+	"github.com/heraque/alloydb-autoscaler/internal/log"
+	"github.com/heraque/alloydb-autoscaler/internal/metrics"
+	"github.com/heraque/alloydb-autoscaler/internal/scaling"
+	"github.com/heraque/alloydb-autoscaler/internal/telemetry"
+)
+
+// supervisor mantém um targetRunner em execução para cada alvo configurado,
+// iniciando e parando runners quando a configuração é recarregada
+type supervisor struct {
+	source metrics.MetricSource
+	api    alloydb.InstanceAPI
+	opCtx  context.Context
+
+	mu  sync.Mutex
+	ctx context.Context
+	// wanted são os alvos da última configuração aplicada
+	wanted map[string]config.Target
+	// runners são os runners ativos; stopping, os cancelados que ainda não
+	// terminaram, por exemplo aguardando uma operação com opCtx
+	runners  map[string]*runnerHandle
+	stopping map[string]*runnerHandle
+	wg       sync.WaitGroup
+}
+
+// runnerHandle identifica uma execução de targetRunner
+type runnerHandle struct {
+	cancel context.CancelFunc
+}
+
+func newSupervisor(source metrics.MetricSource, api alloydb.InstanceAPI, opCtx context.Context) *supervisor {
+	return &supervisor{
+		source:   source,
+		api:      api,
+		opCtx:    opCtx,
+		wanted:   make(map[string]config.Target),
+		runners:  make(map[string]*runnerHandle),
+		stopping: make(map[string]*runnerHandle),
+	}
+}
+
+// reconcile inicia runners para alvos novos e cancela os de alvos removidos.
+// Alvos existentes continuam rodando e leem a configuração nova no próximo ciclo.
+// Um alvo cujo runner anterior ainda está terminando só é iniciado quando
+// esse runner termina, para que dois loops nunca alterem a mesma instância.
+func (s *supervisor) reconcile(ctx context.Context, targets []config.Target) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.ctx = ctx
+	s.wanted = make(map[string]config.Target, len(targets))
+	for _, target := range targets {
+		s.wanted[target.Name] = target
+		if _, running := s.runners[target.Name]; running {
+			continue
+		}
+		if _, stopping := s.stopping[target.Name]; stopping {
+			log.Info().
+				Str("component", "app").
+				Str("action", "start").
+				Str("target", target.Name).
+				Msg("Previous runner for target still stopping, starting when it exits")
+			continue
+		}
+		s.start(target)
+	}
+
+	for name, handle := range s.runners {
+		if _, ok := s.wanted[name]; !ok {
+			handle.cancel()
+			delete(s.runners, name)
+			s.stopping[name] = handle
+		}
+	}
+}
+
+// start inicia o runner de target. Deve ser chamada com s.mu travado.
+func (s *supervisor) start(target config.Target) {
+	runnerCtx, cancel := context.WithCancel(s.ctx)
+	handle := &runnerHandle{cancel: cancel}
+	s.runners[target.Name] = handle
+	runner := newTargetRunner(target.Name, s.source, s.api, s.opCtx)
+	health.TargetStarted(target.Name)
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		defer s.finished(target.Name, handle)
+		log.Info().
+			Str("component", "app").
+			Str("action", "start").
+			Str("target", target.Name).
+			Str("instance", target.InstanceName).
+			Str("cluster", target.ClusterName).
+			Bool("dryRun", target.DryRun).
+			Msg("Starting autoscaler for target")
+		runner.run(runnerCtx)
+	}()
+}
+
+// finished descarta o estado de um alvo removido depois que o runner dele
+// termina, e inicia o runner novo se o alvo voltou a ser configurado nesse
+// meio tempo. No encerramento do processo o estado é mantido.
+func (s *supervisor) finished(name string, handle *runnerHandle) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	handle.cancel()
+
+	restart := false
+	switch {
+	case s.stopping[name] == handle:
+		delete(s.stopping, name)
+		_, restart = s.wanted[name]
+	case s.runners[name] == handle:
+		// O runner terminou sozinho: o alvo saiu da configuração antes da
+		// recarga chegar ao supervisor, ou o processo está encerrando
+		delete(s.runners, name)
+		if s.ctx.Err() != nil {
+			return
+		}
+	}
+
+	telemetry.ForgetTarget(name)
+	health.Forget(name)
+	scaling.Forget(name)
+	metrics.Forget(name)
+
+	if restart && s.ctx.Err() == nil {
+		s.start(s.wanted[name])
+	}
+}
+
+// wait aguarda todos os runners terminarem
+func (s *supervisor) wait() {
+	s.wg.Wait()
+}
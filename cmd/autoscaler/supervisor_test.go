@@ -0,0 +1,112 @@
+package main
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/heraque/alloydb-autoscaler/internal/alloydb"
+	"github.com/heraque/alloydb-autoscaler/internal/config"
+	"github.com/heraque/alloydb-autoscaler/internal/metrics"
+	alloydbapi "google.golang.org/api/alloydb/v1"
+)
+
+// blockingAPI segura GetInstance até release ser fechado e conta quantos
+// runners a executam ao mesmo tempo
+type blockingAPI struct {
+	*alloydb.Fake
+	release chan struct{}
+	entered chan struct{}
+
+	mu        sync.Mutex
+	active    int
+	maxActive int
+}
+
+func (a *blockingAPI) GetInstance(ctx context.Context, name string) (*alloydbapi.Instance, error) {
+	a.mu.Lock()
+	a.active++
+	a.maxActive = max(a.maxActive, a.active)
+	a.mu.Unlock()
+	defer func() {
+		a.mu.Lock()
+		a.active--
+		a.mu.Unlock()
+	}()
+
+	select {
+	case a.entered <- struct{}{}:
+	default:
+	}
+	// Como uma chamada que ignora o contexto, não termina com o
+	// cancelamento do runner
+	<-a.release
+	return a.Fake.GetInstance(ctx, name)
+}
+
+// state informa se o alvo tem um runner ativo e se há um runner terminando
+func (s *supervisor) state(name string) (running, stopping bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, running = s.runners[name]
+	_, stopping = s.stopping[name]
+	return running, stopping
+}
+
+func TestSupervisorRestartsAfterPreviousRunnerStops(t *testing.T) {
+	target := newTestTarget(t)
+	target.CheckInterval = 3600
+	previous := config.Get()
+	c := previous
+	c.Targets = []config.Target{target}
+	config.Set(c)
+	t.Cleanup(func() { config.Set(previous) })
+
+	api := &blockingAPI{
+		Fake:    alloydb.NewFake(),
+		release: make(chan struct{}),
+		entered: make(chan struct{}, 1),
+	}
+	if err := api.AddInstance(target.InstancePath(), 2, 2); err != nil {
+		t.Fatal(err)
+	}
+	source := metrics.NewFakeSource()
+	source.SetSeries(loadMetric, metrics.DoubleSeries(nil, 50))
+	ctx, cancel := context.WithCancel(context.Background())
+	sup := newSupervisor(source, api, context.Background())
+
+	sup.reconcile(ctx, []config.Target{target})
+	select {
+	case <-api.entered:
+	case <-time.After(5 * time.Second):
+		t.Fatal("runner did not start")
+	}
+
+	// O alvo sai e volta à configuração enquanto o runner anterior ainda
+	// aguarda a API
+	sup.reconcile(ctx, nil)
+	sup.reconcile(ctx, []config.Target{target})
+	if running, stopping := sup.state(target.Name); running || !stopping {
+		t.Fatalf("running/stopping = %v/%v, want the new runner to wait for the previous one", running, stopping)
+	}
+
+	close(api.release)
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		running, stopping := sup.state(target.Name)
+		if running && !stopping {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("running/stopping = %v/%v after the previous runner stopped, want the new runner started", running, stopping)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	cancel()
+	sup.wait()
+	if api.maxActive != 1 {
+		t.Errorf("runners active at the same time = %d, want 1", api.maxActive)
+	}
+}
@@ -2,132 +2,155 @@ package alloydb
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"net/http"
 	"time"
 
 	"github.com/heraque/alloydb-autoscaler/internal/config"
-	"github.com/heraque/alloydb-autoscaler/internal/log"
 	"google.golang.org/api/alloydb/v1"
+	"google.golang.org/api/googleapi"
 	"google.golang.org/api/option"
 )
 
-// getInstanceName retorna o nome completo da instância no formato GCP
-func getInstanceName() string {
-	return fmt.Sprintf("projects/%s/locations/%s/clusters/%s/instances/%s",
-		config.Get().GCPProject,
-		config.Get().Region,
-		config.Get().ClusterName,
-		config.Get().InstanceName)
+// ErrConflict indica que a instância mudou depois da leitura do snapshot usado
+// no PATCH, que por isso foi rejeitado
+var ErrConflict = errors.New("instance changed since it was read")
+
+// nodeCountMask restringe o PATCH ao número de nós do read pool, preservando
+// alterações feitas em outros campos pelo console ou pelo Terraform
+const nodeCountMask = "readPoolConfig.nodeCount"
+
+// InstanceAPI abstrai as chamadas à API do AlloyDB usadas pelo autoscaler,
+// permitindo substituir o serviço real por uma implementação em memória
+type InstanceAPI interface {
+	GetCluster(ctx context.Context, name string) (*alloydb.Cluster, error)
+	GetInstance(ctx context.Context, name string) (*alloydb.Instance, error)
+	// PatchInstance altera apenas os campos listados em updateMask, separados
+	// por vírgula
+	PatchInstance(ctx context.Context, name string, instance *alloydb.Instance, updateMask string) (*alloydb.Operation, error)
+	GetOperation(ctx context.Context, name string) (*alloydb.Operation, error)
+	// ListOperations devolve as operações não concluídas cujo alvo é a
+	// instância informada
+	ListOperations(ctx context.Context, instance string) ([]*alloydb.Operation, error)
 }
 
-// createAlloyDBService cria e retorna um cliente de serviço AlloyDB
-func createAlloyDBService(ctx context.Context) (*alloydb.Service, error) {
-	return alloydb.NewService(ctx, option.WithCredentialsFile(config.Get().GoogleApplicationCredentials))
+// Nomes dos métodos de InstanceAPI, usados em métricas e na injeção de falhas do Fake
+const (
+	MethodGetCluster     = "GetCluster"
+	MethodGetInstance    = "GetInstance"
+	MethodPatchInstance  = "PatchInstance"
+	MethodGetOperation   = "GetOperation"
+	MethodListOperations = "ListOperations"
+)
+
+// serviceAPI implementa InstanceAPI usando o serviço AlloyDB do GCP. O
+// serviço é criado uma única vez, de modo que o transporte HTTP, as conexões
+// abertas e o token das credenciais são reaproveitados em todas as chamadas.
+type serviceAPI struct {
+	service *alloydb.Service
+}
+
+// NewInstanceAPI cria o cliente do AlloyDB usado durante toda a vida do
+// processo. Sem credentialsFile, as credenciais padrão do ambiente são usadas.
+func NewInstanceAPI(credentialsFile string) (InstanceAPI, error) {
+	var opts []option.ClientOption
+	if credentialsFile != "" {
+		opts = append(opts, option.WithCredentialsFile(credentialsFile))
+	}
+	// O contexto do serviço também é usado na renovação do token, que precisa
+	// continuar funcionando durante o período de graça do encerramento
+	service, err := alloydb.NewService(context.Background(), opts...)
+	if err != nil {
+		return nil, fmt.Errorf("error creating AlloyDB service: %w", err)
+	}
+	return serviceAPI{service: service}, nil
+}
+
+// callContext limita cada chamada a TIMEOUT_SECONDS, inclusive as feitas fora
+// do ciclo de verificação, como a consulta de operações em andamento
+func callContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	return context.WithTimeout(ctx, time.Duration(config.Get().TimeoutSeconds)*time.Second)
 }
 
 // handleError processa erros comuns, incluindo timeouts
 func handleError(ctx context.Context, err error, operation string) error {
-	if ctx.Err() == context.DeadlineExceeded {
+	if ctx.Err() == context.DeadlineExceeded || errors.Is(err, context.DeadlineExceeded) {
 		return fmt.Errorf("timeout %s: %w", operation, err)
 	}
 	return fmt.Errorf("error %s: %w", operation, err)
 }
 
-// GetReadPoolNodeCount returns the current number of nodes in the read pool
-func GetReadPoolNodeCount(ctx context.Context) (int, error) {
-	service, err := alloydb.NewService(ctx, option.WithCredentialsFile(config.Get().GoogleApplicationCredentials))
-	if err != nil {
-		return 0, fmt.Errorf("error creating AlloyDB service: %w", err)
-	}
-
-	instanceName := getInstanceName()
-	instance, err := service.Projects.Locations.Clusters.Instances.Get(instanceName).Context(ctx).Do()
-	if err != nil {
-		if ctx.Err() == context.DeadlineExceeded {
-			return 0, fmt.Errorf("timeout getting instance: %w", err)
-		}
-		return 0, fmt.Errorf("error getting instance: %w", err)
-	}
-
-	return int(instance.ReadPoolConfig.NodeCount), nil
+func (a serviceAPI) GetCluster(ctx context.Context, name string) (*alloydb.Cluster, error) {
+	ctx, cancel := callContext(ctx)
+	defer cancel()
+	return a.service.Projects.Locations.Clusters.Get(name).Context(ctx).Do()
 }
 
-// GetTotalMemory returns the total memory of the instance in GB
-func GetTotalMemory(ctx context.Context) (float64, error) {
-	service, err := alloydb.NewService(ctx, option.WithCredentialsFile(config.Get().GoogleApplicationCredentials))
-	if err != nil {
-		return 0, fmt.Errorf("error creating AlloyDB service: %w", err)
-	}
+func (a serviceAPI) GetInstance(ctx context.Context, name string) (*alloydb.Instance, error) {
+	ctx, cancel := callContext(ctx)
+	defer cancel()
+	return a.service.Projects.Locations.Clusters.Instances.Get(name).Context(ctx).Do()
+}
 
-	instanceName := getInstanceName()
-	instance, err := service.Projects.Locations.Clusters.Instances.Get(instanceName).Context(ctx).Do()
-	if err != nil {
-		if ctx.Err() == context.DeadlineExceeded {
-			return 0, fmt.Errorf("timeout getting instance for total memory: %w", err)
-		}
-		return 0, fmt.Errorf("error getting instance for total memory: %w", err)
-	}
+func (a serviceAPI) PatchInstance(ctx context.Context, name string, instance *alloydb.Instance, updateMask string) (*alloydb.Operation, error) {
+	ctx, cancel := callContext(ctx)
+	defer cancel()
+	return a.service.Projects.Locations.Clusters.Instances.Patch(name, instance).UpdateMask(updateMask).Context(ctx).Do()
+}
 
-	totalMemoryGB := float64(instance.MachineConfig.CpuCount) * 8
+func (a serviceAPI) GetOperation(ctx context.Context, name string) (*alloydb.Operation, error) {
+	ctx, cancel := callContext(ctx)
+	defer cancel()
+	return a.service.Projects.Locations.Operations.Get(name).Context(ctx).Do()
+}
 
-	return totalMemoryGB, nil
+// ListOperations filtra no servidor, para não paginar todas as operações da
+// localização
+func (a serviceAPI) ListOperations(ctx context.Context, instance string) ([]*alloydb.Operation, error) {
+	ctx, cancel := callContext(ctx)
+	defer cancel()
+	filter := fmt.Sprintf("done = false AND metadata.target = %q", instance)
+	var operations []*alloydb.Operation
+	err := a.service.Projects.Locations.Operations.List(locationOf(instance)).Filter(filter).Pages(ctx, func(page *alloydb.ListOperationsResponse) error {
+		operations = append(operations, page.Operations...)
+		return nil
+	})
+	return operations, err
 }
 
-// UpdateReplicaCount updates the number of replicas in the read pool
-func UpdateReplicaCount(ctx context.Context, count int) (*alloydb.Operation, error) {
-	service, err := alloydb.NewService(ctx, option.WithCredentialsFile(config.Get().GoogleApplicationCredentials))
-	if err != nil {
-		return nil, fmt.Errorf("error creating AlloyDB service: %w", err)
+// UpdateReplicaCount altera o número de nós do read pool. Só
+// readPoolConfig.nodeCount é enviado, protegido pelo etag do snapshot: quando
+// a instância mudou depois da leitura do snapshot, o erro encapsula ErrConflict.
+func UpdateReplicaCount(ctx context.Context, api InstanceAPI, snapshot InstanceSnapshot, count int) (*alloydb.Operation, error) {
+	if snapshot.instance == nil || snapshot.instance.ReadPoolConfig == nil {
+		return nil, fmt.Errorf("instance %s has no read pool configuration", snapshot.Name)
 	}
-
-	instanceName := getInstanceName()
-	instance, err := service.Projects.Locations.Clusters.Instances.Get(instanceName).Context(ctx).Do()
-	if err != nil {
-		return nil, fmt.Errorf("error getting instance: %w", err)
+	instance := &alloydb.Instance{
+		Etag: snapshot.Etag,
+		ReadPoolConfig: &alloydb.ReadPoolConfig{
+			NodeCount:       int64(count),
+			ForceSendFields: []string{"NodeCount"},
+		},
 	}
 
-	instance.ReadPoolConfig.NodeCount = int64(count)
-	operation, err := service.Projects.Locations.Clusters.Instances.Patch(instanceName, instance).Context(ctx).Do()
+	operation, err := api.PatchInstance(ctx, snapshot.Name, instance, nodeCountMask)
 	if err != nil {
+		if isConflict(err) {
+			return nil, fmt.Errorf("%w: %w", ErrConflict, err)
+		}
 		return nil, fmt.Errorf("error initiating replica update operation: %w", err)
 	}
 
 	return operation, nil
 }
 
-// WaitForOperation waits for an AlloyDB operation to complete
-func WaitForOperation(ctx context.Context, operation *alloydb.Operation) error {
-	service, err := alloydb.NewService(ctx, option.WithCredentialsFile(config.Get().GoogleApplicationCredentials))
-	if err != nil {
-		return fmt.Errorf("error creating AlloyDB service: %w", err)
-	}
-
-	log.Info().
-		Str("component", "alloydb").
-		Str("action", "operation").
-		Str("operationName", operation.Name).
-		Msg("Operation in progress. Waiting...")
-
-	startTime := time.Now()
-	for {
-		op, err := service.Projects.Locations.Operations.Get(operation.Name).Context(ctx).Do()
-		if err != nil {
-			return fmt.Errorf("error getting operation status: %w", err)
-		}
-
-		if op.Done {
-			if op.Error != nil {
-				return fmt.Errorf("operation failed: %s", op.Error.Message)
-			}
-			log.Info().
-				Str("component", "alloydb").
-				Str("action", "operation").
-				Str("operationName", operation.Name).
-				Str("duration", fmt.Sprintf("%.2fs", time.Since(startTime).Seconds())).
-				Msg("Operation completed successfully")
-			return nil
-		}
-
-		time.Sleep(10 * time.Second)
+// isConflict identifica a rejeição de um PATCH por etag desatualizado. A API
+// responde ABORTED (409) ou FAILED_PRECONDITION (412), conforme o AIP-154.
+func isConflict(err error) bool {
+	var apiErr *googleapi.Error
+	if !errors.As(err, &apiErr) {
+		return false
 	}
+	return apiErr.Code == http.StatusConflict || apiErr.Code == http.StatusPreconditionFailed
 }
@@ -0,0 +1,362 @@
+package alloydb
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"google.golang.org/api/alloydb/v1"
+	"google.golang.org/api/googleapi"
+)
+
+// FakePatch registra um PATCH recebido pelo Fake
+type FakePatch struct {
+	Instance      string
+	OperationName string
+	FromNodeCount int
+	ToNodeCount   int
+	UpdateMask    string
+	Time          time.Time
+}
+
+// fakeOperation guarda o estado simulado de uma operação de longa duração
+type fakeOperation struct {
+	op        *alloydb.Operation
+	instance  string
+	nodeCount int64
+	doneAt    time.Time
+	failure   string
+}
+
+// Fake é uma implementação em memória de InstanceAPI. Simula o estado das
+// instâncias, o número de nós do read pool e operações de longa duração com
+// latência e falhas configuráveis.
+type Fake struct {
+	// OperationLatency é o tempo até uma operação ser reportada como concluída
+	OperationLatency time.Duration
+	// Now permite controlar o relógio usado para concluir operações
+	Now func() time.Time
+
+	mu         sync.Mutex
+	clusters   map[string]*alloydb.Cluster
+	instances  map[string]*alloydb.Instance
+	operations map[string]*fakeOperation
+	failures   map[string][]error
+	opFailures []string
+	calls      map[string]int
+	patches    []FakePatch
+	opSeq      int
+	etagSeq    int
+}
+
+var _ InstanceAPI = (*Fake)(nil)
+
+// NewFake cria um Fake vazio com operações instantâneas
+func NewFake() *Fake {
+	return &Fake{
+		Now:        time.Now,
+		clusters:   make(map[string]*alloydb.Cluster),
+		instances:  make(map[string]*alloydb.Instance),
+		operations: make(map[string]*fakeOperation),
+		failures:   make(map[string][]error),
+		calls:      make(map[string]int),
+	}
+}
+
+// AddInstance registra uma instância READY com o número de nós e vCPUs
+// informados, e o cluster dela, também READY, caso ainda não exista
+func (f *Fake) AddInstance(name string, nodeCount, cpuCount int) error {
+	clusterName, _, _ := strings.Cut(name, "/instances/")
+	f.mu.Lock()
+	if _, ok := f.clusters[clusterName]; !ok {
+		f.clusters[clusterName] = &alloydb.Cluster{Name: clusterName, State: "READY"}
+	}
+	f.mu.Unlock()
+	return f.SetInstance(&alloydb.Instance{
+		Name:           name,
+		State:          "READY",
+		InstanceType:   "READ_POOL",
+		MachineConfig:  &alloydb.MachineConfig{CpuCount: int64(cpuCount)},
+		ReadPoolConfig: &alloydb.ReadPoolConfig{NodeCount: int64(nodeCount)},
+	})
+}
+
+// SetCluster registra ou substitui um cluster
+func (f *Fake) SetCluster(cluster *alloydb.Cluster) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	copied := *cluster
+	f.clusters[cluster.Name] = &copied
+}
+
+// SetInstance registra ou substitui uma instância, como uma alteração feita
+// fora do autoscaler. O etag da instância é renovado.
+func (f *Fake) SetInstance(instance *alloydb.Instance) error {
+	copied, err := copyInstance(instance)
+	if err != nil {
+		return err
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.instances[instance.Name] = copied
+	f.touch(copied)
+	return nil
+}
+
+// Instance retorna uma cópia do estado atual da instância
+func (f *Fake) Instance(name string) (*alloydb.Instance, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.settle()
+	instance, ok := f.instances[name]
+	if !ok {
+		return nil, notFound("instance", name)
+	}
+	return copyInstance(instance)
+}
+
+// NodeCount retorna o número atual de nós do read pool da instância, ou 0 se
+// ela não existir ou não tiver read pool
+func (f *Fake) NodeCount(name string) int {
+	instance, err := f.Instance(name)
+	if err != nil || instance.ReadPoolConfig == nil {
+		return 0
+	}
+	return int(instance.ReadPoolConfig.NodeCount)
+}
+
+// FailNext faz a próxima chamada ao método informado retornar err. Chamadas
+// sucessivas enfileiram falhas adicionais.
+func (f *Fake) FailNext(method string, err error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.failures[method] = append(f.failures[method], err)
+}
+
+// FailNextOperation faz a próxima operação criada terminar com erro
+func (f *Fake) FailNextOperation(message string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.opFailures = append(f.opFailures, message)
+}
+
+// Calls retorna quantas vezes o método informado foi chamado
+func (f *Fake) Calls(method string) int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.calls[method]
+}
+
+// Patches retorna o histórico de PATCHes aceitos
+func (f *Fake) Patches() []FakePatch {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]FakePatch(nil), f.patches...)
+}
+
+func (f *Fake) GetCluster(ctx context.Context, name string) (*alloydb.Cluster, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.begin(ctx, MethodGetCluster); err != nil {
+		return nil, err
+	}
+
+	cluster, ok := f.clusters[name]
+	if !ok {
+		return nil, notFound("cluster", name)
+	}
+	copied := *cluster
+	return &copied, nil
+}
+
+func (f *Fake) GetInstance(ctx context.Context, name string) (*alloydb.Instance, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.begin(ctx, MethodGetInstance); err != nil {
+		return nil, err
+	}
+	f.settle()
+
+	instance, ok := f.instances[name]
+	if !ok {
+		return nil, notFound("instance", name)
+	}
+	return copyInstance(instance)
+}
+
+func (f *Fake) PatchInstance(ctx context.Context, name string, instance *alloydb.Instance, updateMask string) (*alloydb.Operation, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.begin(ctx, MethodPatchInstance); err != nil {
+		return nil, err
+	}
+	f.settle()
+
+	current, ok := f.instances[name]
+	if !ok {
+		return nil, notFound("instance", name)
+	}
+	if current.Reconciling {
+		return nil, &googleapi.Error{
+			Code:    http.StatusConflict,
+			Message: fmt.Sprintf("an operation is already in progress on instance %s", name),
+		}
+	}
+	if instance.Etag != "" && instance.Etag != current.Etag {
+		return nil, &googleapi.Error{
+			Code:    http.StatusConflict,
+			Message: fmt.Sprintf("etag %s does not match the current etag of instance %s", instance.Etag, name),
+		}
+	}
+	// O Fake só simula alterações no número de nós do read pool
+	if updateMask != nodeCountMask {
+		return nil, &googleapi.Error{Code: http.StatusBadRequest, Message: fmt.Sprintf("unsupported update mask %q", updateMask)}
+	}
+	if instance.ReadPoolConfig == nil {
+		return nil, &googleapi.Error{Code: http.StatusBadRequest, Message: "readPoolConfig is required"}
+	}
+	if current.ReadPoolConfig == nil {
+		return nil, &googleapi.Error{
+			Code:    http.StatusBadRequest,
+			Message: fmt.Sprintf("instance %s has no read pool", name),
+		}
+	}
+
+	f.opSeq++
+	opName := fmt.Sprintf("%s/operations/operation-%d", locationOf(name), f.opSeq)
+	now := f.Now()
+	metadata, _ := json.Marshal(alloydb.OperationMetadata{
+		Target:     name,
+		Verb:       "update",
+		CreateTime: now.UTC().Format(time.RFC3339Nano),
+	})
+	fop := &fakeOperation{
+		op:        &alloydb.Operation{Name: opName, Metadata: metadata},
+		instance:  name,
+		nodeCount: instance.ReadPoolConfig.NodeCount,
+		doneAt:    now.Add(f.OperationLatency),
+	}
+	if len(f.opFailures) > 0 {
+		fop.failure = f.opFailures[0]
+		f.opFailures = f.opFailures[1:]
+	}
+	f.operations[opName] = fop
+	current.Reconciling = true
+	f.touch(current)
+
+	f.patches = append(f.patches, FakePatch{
+		Instance:      name,
+		OperationName: opName,
+		FromNodeCount: int(current.ReadPoolConfig.NodeCount),
+		ToNodeCount:   int(instance.ReadPoolConfig.NodeCount),
+		UpdateMask:    updateMask,
+		Time:          now,
+	})
+
+	op := *fop.op
+	return &op, nil
+}
+
+func (f *Fake) GetOperation(ctx context.Context, name string) (*alloydb.Operation, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.begin(ctx, MethodGetOperation); err != nil {
+		return nil, err
+	}
+	f.settle()
+
+	fop, ok := f.operations[name]
+	if !ok {
+		return nil, notFound("operation", name)
+	}
+	op := *fop.op
+	return &op, nil
+}
+
+func (f *Fake) ListOperations(ctx context.Context, instance string) ([]*alloydb.Operation, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.begin(ctx, MethodListOperations); err != nil {
+		return nil, err
+	}
+	f.settle()
+
+	var operations []*alloydb.Operation
+	for _, fop := range f.operations {
+		if !fop.op.Done && fop.instance == instance {
+			op := *fop.op
+			operations = append(operations, &op)
+		}
+	}
+	return operations, nil
+}
+
+// begin contabiliza a chamada e aplica cancelamento e falhas injetadas
+func (f *Fake) begin(ctx context.Context, method string) error {
+	f.calls[method]++
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if queued := f.failures[method]; len(queued) > 0 {
+		f.failures[method] = queued[1:]
+		return queued[0]
+	}
+	return nil
+}
+
+// settle conclui as operações cuja latência já expirou
+func (f *Fake) settle() {
+	now := f.Now()
+	for _, fop := range f.operations {
+		if fop.op.Done || now.Before(fop.doneAt) {
+			continue
+		}
+		fop.op.Done = true
+		metadata := operationMetadata(fop.op)
+		metadata.EndTime = fop.doneAt.UTC().Format(time.RFC3339Nano)
+		fop.op.Metadata, _ = json.Marshal(metadata)
+		instance := f.instances[fop.instance]
+		if instance != nil {
+			instance.Reconciling = false
+			f.touch(instance)
+		}
+		if fop.failure != "" {
+			fop.op.Error = &alloydb.Status{Code: 13, Message: fop.failure}
+			continue
+		}
+		if instance != nil && instance.ReadPoolConfig != nil {
+			instance.ReadPoolConfig.NodeCount = fop.nodeCount
+			instance.UpdateTime = now.UTC().Format(time.RFC3339Nano)
+		}
+	}
+}
+
+// touch renova o etag da instância, como a API faz a cada alteração
+func (f *Fake) touch(instance *alloydb.Instance) {
+	f.etagSeq++
+	instance.Etag = fmt.Sprintf("etag-%d", f.etagSeq)
+}
+
+func notFound(kind, name string) error {
+	return &googleapi.Error{
+		Code:    http.StatusNotFound,
+		Message: fmt.Sprintf("%s %s not found", kind, name),
+	}
+}
+
+// copyInstance devolve uma cópia profunda para que chamadores não alterem o estado do Fake
+func copyInstance(instance *alloydb.Instance) (*alloydb.Instance, error) {
+	data, err := json.Marshal(instance)
+	if err != nil {
+		return nil, fmt.Errorf("error copying instance: %w", err)
+	}
+	var copied alloydb.Instance
+	if err := json.Unmarshal(data, &copied); err != nil {
+		return nil, fmt.Errorf("error copying instance: %w", err)
+	}
+	return &copied, nil
+}
@@ -0,0 +1,75 @@
+package alloydb
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/heraque/alloydb-autoscaler/internal/config"
+	"google.golang.org/api/alloydb/v1"
+	"google.golang.org/api/googleapi"
+)
+
+var testTarget = config.Target{
+	GCPProject:   "project",
+	Region:       "region",
+	ClusterName:  "cluster",
+	InstanceName: "read-pool",
+}
+
+func TestUpdateReplicaCount(t *testing.T) {
+	ctx := context.Background()
+	OperationPollInterval = 0
+	api := NewFake()
+	if err := api.AddInstance(testTarget.InstancePath(), 2, 2); err != nil {
+		t.Fatal(err)
+	}
+
+	snapshot, err := GetSnapshot(ctx, api, testTarget)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	operation, err := UpdateReplicaCount(ctx, api, snapshot, 4)
+	if err != nil {
+		t.Fatalf("UpdateReplicaCount() error = %v", err)
+	}
+	if _, err := WaitForOperation(ctx, api, operation); err != nil {
+		t.Fatalf("WaitForOperation() error = %v", err)
+	}
+	if got := api.NodeCount(testTarget.InstancePath()); got != 4 {
+		t.Errorf("node count = %d, want 4", got)
+	}
+	patches := api.Patches()
+	if len(patches) != 1 || patches[0].UpdateMask != nodeCountMask || patches[0].FromNodeCount != 2 {
+		t.Errorf("patches = %+v, want one %s patch from 2 nodes", patches, nodeCountMask)
+	}
+
+	// O snapshot anterior ao PATCH tem o etag desatualizado
+	if _, err := UpdateReplicaCount(ctx, api, snapshot, 3); !errors.Is(err, ErrConflict) {
+		t.Errorf("UpdateReplicaCount() with a stale etag error = %v, want ErrConflict", err)
+	}
+}
+
+func TestFakeInstanceWithoutReadPool(t *testing.T) {
+	ctx := context.Background()
+	api := NewFake()
+	name := "projects/project/locations/region/clusters/cluster/instances/primary"
+	if err := api.SetInstance(&alloydb.Instance{Name: name, State: "READY", InstanceType: "PRIMARY"}); err != nil {
+		t.Fatal(err)
+	}
+
+	patch := &alloydb.Instance{ReadPoolConfig: &alloydb.ReadPoolConfig{NodeCount: 2}}
+	_, err := api.PatchInstance(ctx, name, patch, nodeCountMask)
+	var apiErr *googleapi.Error
+	if !errors.As(err, &apiErr) || apiErr.Code != http.StatusBadRequest {
+		t.Errorf("PatchInstance() error = %v, want a 400 googleapi.Error", err)
+	}
+	if got := api.NodeCount(name); got != 0 {
+		t.Errorf("NodeCount() = %d, want 0", got)
+	}
+	if _, err := api.Instance("missing"); err == nil {
+		t.Error("Instance() of a missing instance error = nil")
+	}
+}
@@ -0,0 +1,71 @@
+package alloydb
+
+import (
+	"context"
+	"time"
+
+	"github.com/heraque/alloydb-autoscaler/internal/health"
+	"github.com/heraque/alloydb-autoscaler/internal/resilience"
+	"github.com/heraque/alloydb-autoscaler/internal/telemetry"
+	"google.golang.org/api/alloydb/v1"
+)
+
+// instrumentedAPI registra latência e erros de cada chamada à InstanceAPI e
+// alimenta o estado de alcançabilidade usado em /readyz
+type instrumentedAPI struct {
+	next InstanceAPI
+}
+
+// Instrument envolve a InstanceAPI para expor métricas de latência e erros e
+// o estado de saúde da API
+func Instrument(api InstanceAPI) InstanceAPI {
+	return &instrumentedAPI{next: api}
+}
+
+func (a *instrumentedAPI) GetCluster(ctx context.Context, name string) (*alloydb.Cluster, error) {
+	start := time.Now()
+	cluster, err := a.next.GetCluster(ctx, name)
+	observe(MethodGetCluster, start, err)
+	return cluster, err
+}
+
+func (a *instrumentedAPI) GetInstance(ctx context.Context, name string) (*alloydb.Instance, error) {
+	start := time.Now()
+	instance, err := a.next.GetInstance(ctx, name)
+	observe(MethodGetInstance, start, err)
+	return instance, err
+}
+
+func (a *instrumentedAPI) PatchInstance(ctx context.Context, name string, instance *alloydb.Instance, updateMask string) (*alloydb.Operation, error) {
+	start := time.Now()
+	op, err := a.next.PatchInstance(ctx, name, instance, updateMask)
+	observe(MethodPatchInstance, start, err)
+	return op, err
+}
+
+func (a *instrumentedAPI) GetOperation(ctx context.Context, name string) (*alloydb.Operation, error) {
+	start := time.Now()
+	op, err := a.next.GetOperation(ctx, name)
+	observe(MethodGetOperation, start, err)
+	return op, err
+}
+
+func (a *instrumentedAPI) ListOperations(ctx context.Context, instance string) ([]*alloydb.Operation, error) {
+	start := time.Now()
+	ops, err := a.next.ListOperations(ctx, instance)
+	observe(MethodListOperations, start, err)
+	return ops, err
+}
+
+// observe registra a chamada nas métricas e no estado de saúde. Só erros
+// transitórios, de cota ou de circuito aberto indicam que a API está
+// inacessível.
+func observe(method string, start time.Time, err error) {
+	telemetry.ObserveAPICall("alloydb", method, start, err)
+	switch resilience.Classify(err) {
+	case resilience.ClassRetryable, resilience.ClassQuota, resilience.ClassCircuitOpen:
+		health.AlloyDBCall(err, true)
+	default:
+		health.AlloyDBCall(err, false)
+	}
+}
@@ -0,0 +1,55 @@
+package alloydb
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/heraque/alloydb-autoscaler/internal/health"
+	"google.golang.org/api/googleapi"
+)
+
+func TestInstrumentReachability(t *testing.T) {
+	tests := []struct {
+		name          string
+		err           error
+		cancel        bool
+		wantReachable bool
+	}{
+		{name: "unavailable", err: &googleapi.Error{Code: http.StatusServiceUnavailable}},
+		{name: "quota", err: &googleapi.Error{Code: http.StatusTooManyRequests}},
+		{name: "not found", err: &googleapi.Error{Code: http.StatusNotFound}, wantReachable: true},
+		{name: "conflict", err: &googleapi.Error{Code: http.StatusConflict}, wantReachable: true},
+		{name: "permanent", err: errors.New("invalid request"), wantReachable: true},
+		{name: "canceled context", cancel: true, wantReachable: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := NewFake()
+			if err := fake.AddInstance(testTarget.InstancePath(), 2, 2); err != nil {
+				t.Fatal(err)
+			}
+			api := Instrument(fake)
+			if _, err := api.GetInstance(context.Background(), testTarget.InstancePath()); err != nil {
+				t.Fatal(err)
+			}
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			if tt.cancel {
+				cancel()
+			} else {
+				fake.FailNext(MethodGetInstance, tt.err)
+			}
+			if _, err := api.GetInstance(ctx, testTarget.InstancePath()); err == nil {
+				t.Fatal("GetInstance() error = nil")
+			}
+
+			status := health.Readiness()
+			if status.AlloyDB == nil || status.AlloyDB.Reachable != tt.wantReachable {
+				t.Errorf("AlloyDB = %+v, want reachable %v", status.AlloyDB, tt.wantReachable)
+			}
+		})
+	}
+}
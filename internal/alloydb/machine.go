@@ -0,0 +1,32 @@
+package alloydb
+
+// memoryPerVCPUGB é a proporção de memória da maioria dos formatos de máquina
+// do AlloyDB, usada apenas quando o número de vCPUs não está no catálogo
+const memoryPerVCPUGB = 8
+
+// machineMemoryGB é a memória, em GB, de cada formato de máquina do AlloyDB
+// (N2 e C4A) pelo número de vCPUs. O formato de 128 vCPUs não segue a
+// proporção de 8 GB por vCPU.
+var machineMemoryGB = map[int64]float64{
+	1:   8,
+	2:   16,
+	4:   32,
+	8:   64,
+	16:  128,
+	32:  256,
+	48:  384,
+	64:  512,
+	72:  576,
+	96:  768,
+	128: 864,
+}
+
+// MachineMemoryGB devolve a memória do formato de máquina com cpuCount vCPUs.
+// O segundo resultado é falso quando o formato não está no catálogo e a
+// memória foi estimada em 8 GB por vCPU.
+func MachineMemoryGB(cpuCount int64) (float64, bool) {
+	if memory, ok := machineMemoryGB[cpuCount]; ok {
+		return memory, true
+	}
+	return float64(cpuCount) * memoryPerVCPUGB, false
+}
@@ -0,0 +1,212 @@
+package alloydb
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"strings"
+	"time"
+
+	"github.com/heraque/alloydb-autoscaler/internal/config"
+	"github.com/heraque/alloydb-autoscaler/internal/log"
+	"github.com/heraque/alloydb-autoscaler/internal/resilience"
+	"google.golang.org/api/alloydb/v1"
+)
+
+// OperationPollInterval é o intervalo da primeira consulta ao status de uma
+// operação. A cada consulta o intervalo dobra, até OperationMaxPollInterval.
+var (
+	OperationPollInterval    = 5 * time.Second
+	OperationMaxPollInterval = time.Minute
+)
+
+// pollJitter é a variação aleatória aplicada a cada intervalo, para que
+// vários alvos não consultem a API no mesmo instante
+const pollJitter = 0.2
+
+// ErrOperationMaxWait indica que uma operação não terminou dentro de
+// OPERATION_MAX_WAIT_SECONDS. A operação pode continuar em andamento no AlloyDB.
+var ErrOperationMaxWait = errors.New("operation did not finish within the maximum wait")
+
+// ErrOperationFailed indica que a operação terminou com erro
+var ErrOperationFailed = errors.New("operation failed")
+
+// WaitForOperation aguarda a conclusão de uma operação do AlloyDB e devolve o
+// horário em que ela terminou. O status é consultado com backoff exponencial
+// e variação aleatória, por no máximo OPERATION_MAX_WAIT_SECONDS.
+func WaitForOperation(ctx context.Context, api InstanceAPI, operation *alloydb.Operation) (time.Time, error) {
+	maxWait := time.Duration(config.Get().OperationMaxWaitSeconds) * time.Second
+	if maxWait > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeoutCause(ctx, maxWait, ErrOperationMaxWait)
+		defer cancel()
+	}
+
+	startTime := time.Now()
+	var (
+		status  string
+		started bool
+	)
+	for attempt := 0; ; attempt++ {
+		op, err := api.GetOperation(ctx, operation.Name)
+		if err != nil {
+			if errors.Is(context.Cause(ctx), ErrOperationMaxWait) {
+				return time.Time{}, fmt.Errorf("%w (%s)", ErrOperationMaxWait, maxWait)
+			}
+			// Uma consulta que falhou não diz nada sobre a operação: erros
+			// transitórios apenas adiam a próxima consulta
+			class := resilience.Classify(err)
+			if ctx.Err() != nil || !class.Retryable() && class != resilience.ClassCircuitOpen {
+				return time.Time{}, fmt.Errorf("error getting operation status: %w", err)
+			}
+			log.Warn().
+				Str("component", "alloydb").
+				Str("action", "operation").
+				Str("operationName", operation.Name).
+				Str("errorClass", string(class)).
+				Str("error", err.Error()).
+				Msg("Failed to get operation status, polling again")
+			if !sleep(ctx, pollDelay(attempt)) {
+				return time.Time{}, waitError(ctx, maxWait)
+			}
+			continue
+		}
+
+		metadata := operationMetadata(op)
+		if !started {
+			// Os metadados da primeira consulta identificam a operação, mesmo
+			// quando ela foi retomada apenas pelo nome
+			started = true
+			status = metadata.StatusMessage
+			log.Info().
+				Str("component", "alloydb").
+				Str("action", "operation").
+				Str("operationName", operation.Name).
+				Str("verb", metadata.Verb).
+				Str("resource", metadata.Target).
+				Str("status", status).
+				Str("maxWait", maxWait.String()).
+				Msg("Operation in progress. Waiting...")
+		} else if metadata.StatusMessage != "" && metadata.StatusMessage != status {
+			status = metadata.StatusMessage
+			log.Info().
+				Str("component", "alloydb").
+				Str("action", "operation").
+				Str("operationName", operation.Name).
+				Str("status", status).
+				Bool("requestedCancellation", metadata.RequestedCancellation).
+				Str("elapsed", fmt.Sprintf("%.2fs", time.Since(startTime).Seconds())).
+				Msg("Operation progress")
+		}
+
+		if op.Done {
+			endTime := operationEndTime(metadata)
+			if op.Error != nil {
+				return endTime, fmt.Errorf("%w: %s", ErrOperationFailed, op.Error.Message)
+			}
+			log.Info().
+				Str("component", "alloydb").
+				Str("action", "operation").
+				Str("operationName", operation.Name).
+				Int("polls", attempt+1).
+				Str("duration", fmt.Sprintf("%.2fs", time.Since(startTime).Seconds())).
+				Msg("Operation completed successfully")
+			return endTime, nil
+		}
+
+		delay := pollDelay(attempt)
+		log.Debug().
+			Str("component", "alloydb").
+			Str("action", "operation").
+			Str("operationName", operation.Name).
+			Int("polls", attempt+1).
+			Str("nextPoll", delay.Round(time.Millisecond).String()).
+			Msg("Operation still running")
+
+		if !sleep(ctx, delay) {
+			return time.Time{}, waitError(ctx, maxWait)
+		}
+	}
+}
+
+// sleep aguarda delay e devolve false se o contexto terminar antes
+func sleep(ctx context.Context, delay time.Duration) bool {
+	select {
+	case <-ctx.Done():
+		return false
+	case <-time.After(delay):
+		return true
+	}
+}
+
+// waitError descreve por que a espera por uma operação terminou antes dela
+func waitError(ctx context.Context, maxWait time.Duration) error {
+	if errors.Is(context.Cause(ctx), ErrOperationMaxWait) {
+		return fmt.Errorf("%w (%s)", ErrOperationMaxWait, maxWait)
+	}
+	return handleError(ctx, ctx.Err(), "waiting for operation")
+}
+
+// ListRunningOperations devolve as operações da instância que ainda não
+// terminaram, inclusive as iniciadas fora do autoscaler. O resultado da API
+// é conferido localmente, caso o filtro não seja aplicado pelo servidor.
+func ListRunningOperations(ctx context.Context, api InstanceAPI, instance string) ([]*alloydb.Operation, error) {
+	operations, err := api.ListOperations(ctx, instance)
+	if err != nil {
+		return nil, handleError(ctx, err, "listing operations")
+	}
+
+	var running []*alloydb.Operation
+	for _, op := range operations {
+		if !op.Done && operationMetadata(op).Target == instance {
+			running = append(running, op)
+		}
+	}
+	return running, nil
+}
+
+// pollDelay devolve o intervalo antes da consulta seguinte à attempt: o
+// intervalo inicial dobrado a cada tentativa, limitado ao máximo, com ±20% de
+// variação aleatória
+func pollDelay(attempt int) time.Duration {
+	delay := OperationPollInterval
+	for i := 0; i < attempt && delay < OperationMaxPollInterval; i++ {
+		delay *= 2
+	}
+	delay = min(delay, OperationMaxPollInterval)
+	if delay <= 0 {
+		return 0
+	}
+	jitter := time.Duration(float64(delay) * pollJitter)
+	return delay - jitter + rand.N(2*jitter+1)
+}
+
+// operationMetadata decodifica os metadados da operação; campos ausentes
+// ficam vazios
+func operationMetadata(op *alloydb.Operation) alloydb.OperationMetadata {
+	var metadata alloydb.OperationMetadata
+	if len(op.Metadata) > 0 {
+		_ = json.Unmarshal(op.Metadata, &metadata)
+	}
+	return metadata
+}
+
+// operationEndTime devolve o horário de término informado nos metadados da
+// operação, ou o horário atual quando os metadados não o incluem
+func operationEndTime(metadata alloydb.OperationMetadata) time.Time {
+	if endTime, err := time.Parse(time.RFC3339Nano, metadata.EndTime); err == nil {
+		return endTime
+	}
+	return time.Now()
+}
+
+// locationOf extrai "projects/<p>/locations/<l>" do nome completo de um recurso
+func locationOf(name string) string {
+	parts := strings.Split(name, "/")
+	if len(parts) >= 4 {
+		return strings.Join(parts[:4], "/")
+	}
+	return name
+}
@@ -0,0 +1,112 @@
+package alloydb
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/heraque/alloydb-autoscaler/internal/config"
+	"google.golang.org/api/googleapi"
+)
+
+func TestPollDelay(t *testing.T) {
+	interval, maxInterval := OperationPollInterval, OperationMaxPollInterval
+	t.Cleanup(func() { OperationPollInterval, OperationMaxPollInterval = interval, maxInterval })
+	OperationPollInterval, OperationMaxPollInterval = time.Second, 8*time.Second
+
+	tests := []struct {
+		attempt int
+		base    time.Duration
+	}{
+		{attempt: 0, base: time.Second},
+		{attempt: 1, base: 2 * time.Second},
+		{attempt: 2, base: 4 * time.Second},
+		{attempt: 3, base: 8 * time.Second},
+		{attempt: 4, base: 8 * time.Second},
+		{attempt: 100, base: 8 * time.Second},
+	}
+	for _, tt := range tests {
+		low := tt.base - time.Duration(float64(tt.base)*pollJitter)
+		high := tt.base + time.Duration(float64(tt.base)*pollJitter)
+		seen := make(map[time.Duration]bool)
+		for range 100 {
+			delay := pollDelay(tt.attempt)
+			if delay < low || delay > high {
+				t.Fatalf("pollDelay(%d) = %s, want between %s and %s", tt.attempt, delay, low, high)
+			}
+			seen[delay] = true
+		}
+		if len(seen) < 2 {
+			t.Errorf("pollDelay(%d) returned the same delay 100 times, want jitter", tt.attempt)
+		}
+	}
+}
+
+func TestPollDelayDisabled(t *testing.T) {
+	interval := OperationPollInterval
+	t.Cleanup(func() { OperationPollInterval = interval })
+	OperationPollInterval = 0
+
+	for _, attempt := range []int{0, 1, 10} {
+		if delay := pollDelay(attempt); delay != 0 {
+			t.Errorf("pollDelay(%d) = %s, want 0", attempt, delay)
+		}
+	}
+}
+
+func TestListRunningOperations(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	api := NewFake()
+	api.Now = func() time.Time { return now }
+	api.OperationLatency = time.Hour
+
+	other := testTarget
+	other.InstanceName = "other-pool"
+	for _, target := range []config.Target{testTarget, other} {
+		if err := api.AddInstance(target.InstancePath(), 2, 2); err != nil {
+			t.Fatal(err)
+		}
+	}
+	patch := func(target config.Target, count int) string {
+		t.Helper()
+		snapshot, err := GetSnapshot(ctx, api, target)
+		if err != nil {
+			t.Fatal(err)
+		}
+		operation, err := UpdateReplicaCount(ctx, api, snapshot, count)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return operation.Name
+	}
+
+	// Uma operação já concluída na instância, outra em andamento e uma
+	// terceira em outra instância da mesma região
+	patch(testTarget, 3)
+	now = now.Add(2 * time.Hour)
+	running := patch(testTarget, 4)
+	patch(other, 3)
+
+	got, err := ListRunningOperations(ctx, api, testTarget.InstancePath())
+	if err != nil {
+		t.Fatalf("ListRunningOperations() error = %v", err)
+	}
+	if len(got) != 1 || got[0].Name != running {
+		names := make([]string, len(got))
+		for i, op := range got {
+			names[i] = op.Name
+		}
+		t.Errorf("ListRunningOperations() = %v, want [%s]", names, running)
+	}
+}
+
+func TestListRunningOperationsError(t *testing.T) {
+	api := NewFake()
+	api.FailNext(MethodListOperations, &googleapi.Error{Code: http.StatusForbidden, Message: "permission denied"})
+
+	if _, err := ListRunningOperations(context.Background(), api, testTarget.InstancePath()); err == nil {
+		t.Error("ListRunningOperations() error = nil, want the API error")
+	}
+}
@@ -0,0 +1,51 @@
+package alloydb
+
+import (
+	"context"
+
+	"github.com/heraque/alloydb-autoscaler/internal/resilience"
+	"google.golang.org/api/alloydb/v1"
+)
+
+// resilientAPI repete as leituras que falham por erro transitório ou de cota
+// e passa todas as chamadas pelo circuit breaker da API do AlloyDB
+type resilientAPI struct {
+	next InstanceAPI
+}
+
+// Resilient envolve a InstanceAPI com novas tentativas e circuit breaker. O
+// PATCH não é repetido: uma tentativa que falhou pode ter iniciado a operação,
+// e a seguinte seria rejeitada pelo etag.
+func Resilient(api InstanceAPI) InstanceAPI {
+	return &resilientAPI{next: api}
+}
+
+func (a *resilientAPI) GetCluster(ctx context.Context, name string) (*alloydb.Cluster, error) {
+	return resilience.Call(ctx, "alloydb", MethodGetCluster, true, func(ctx context.Context) (*alloydb.Cluster, error) {
+		return a.next.GetCluster(ctx, name)
+	})
+}
+
+func (a *resilientAPI) GetInstance(ctx context.Context, name string) (*alloydb.Instance, error) {
+	return resilience.Call(ctx, "alloydb", MethodGetInstance, true, func(ctx context.Context) (*alloydb.Instance, error) {
+		return a.next.GetInstance(ctx, name)
+	})
+}
+
+func (a *resilientAPI) PatchInstance(ctx context.Context, name string, instance *alloydb.Instance, updateMask string) (*alloydb.Operation, error) {
+	return resilience.Call(ctx, "alloydb", MethodPatchInstance, false, func(ctx context.Context) (*alloydb.Operation, error) {
+		return a.next.PatchInstance(ctx, name, instance, updateMask)
+	})
+}
+
+func (a *resilientAPI) GetOperation(ctx context.Context, name string) (*alloydb.Operation, error) {
+	return resilience.Call(ctx, "alloydb", MethodGetOperation, true, func(ctx context.Context) (*alloydb.Operation, error) {
+		return a.next.GetOperation(ctx, name)
+	})
+}
+
+func (a *resilientAPI) ListOperations(ctx context.Context, instance string) ([]*alloydb.Operation, error) {
+	return resilience.Call(ctx, "alloydb", MethodListOperations, true, func(ctx context.Context) ([]*alloydb.Operation, error) {
+		return a.next.ListOperations(ctx, instance)
+	})
+}
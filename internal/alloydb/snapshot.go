@@ -0,0 +1,116 @@
+package alloydb
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/heraque/alloydb-autoscaler/internal/config"
+	"google.golang.org/api/alloydb/v1"
+)
+
+// InstanceSnapshot é o estado de uma instância lido uma vez por ciclo. A
+// verificação de métricas, a decisão de escala e o PATCH usam o mesmo
+// snapshot e, assim, concordam quanto ao número de nós e ao formato da máquina.
+type InstanceSnapshot struct {
+	Name       string
+	NodeCount  int
+	CPUCount   int64
+	State      string
+	Etag       string
+	Labels     map[string]string
+	UpdateTime time.Time
+	FetchedAt  time.Time
+	// Reconciling é verdadeiro enquanto uma operação altera a instância
+	Reconciling bool
+	// ClusterState e ClusterReconciling descrevem o cluster da instância
+	ClusterState       string
+	ClusterReconciling bool
+
+	instance *alloydb.Instance
+}
+
+// GetSnapshot lê uma vez a instância do alvo e o cluster dela. O snapshot é
+// obtido no início de um ciclo e reutilizado até o próximo.
+func GetSnapshot(ctx context.Context, api InstanceAPI, target config.Target) (InstanceSnapshot, error) {
+	instance, err := api.GetInstance(ctx, target.InstancePath())
+	if err != nil {
+		return InstanceSnapshot{}, handleError(ctx, err, "getting instance")
+	}
+	cluster, err := api.GetCluster(ctx, target.ClusterPath())
+	if err != nil {
+		return InstanceSnapshot{}, handleError(ctx, err, "getting cluster")
+	}
+
+	s := newSnapshot(instance)
+	s.ClusterState = cluster.State
+	s.ClusterReconciling = cluster.Reconciling
+	return s, nil
+}
+
+// NotReady devolve o motivo pelo qual as decisões devem aguardar a instância,
+// ou "" quando a instância e o cluster estão READY e nenhuma operação os altera
+func (s InstanceSnapshot) NotReady() string {
+	switch {
+	case s.State != "READY":
+		return fmt.Sprintf("instance state is %s", s.State)
+	case s.ClusterState != "READY":
+		return fmt.Sprintf("cluster state is %s", s.ClusterState)
+	case s.Reconciling:
+		return "an operation is running on the instance"
+	case s.ClusterReconciling:
+		return "an operation is running on the cluster"
+	}
+	return ""
+}
+
+func newSnapshot(instance *alloydb.Instance) InstanceSnapshot {
+	s := InstanceSnapshot{
+		Name:        instance.Name,
+		State:       instance.State,
+		Etag:        instance.Etag,
+		Labels:      instance.Labels,
+		FetchedAt:   time.Now(),
+		Reconciling: instance.Reconciling,
+		instance:    instance,
+	}
+	if instance.ReadPoolConfig != nil {
+		s.NodeCount = int(instance.ReadPoolConfig.NodeCount)
+	}
+	if instance.MachineConfig != nil {
+		s.CPUCount = instance.MachineConfig.CpuCount
+	}
+	if updated, err := time.Parse(time.RFC3339Nano, instance.UpdateTime); err == nil {
+		s.UpdateTime = updated
+	}
+	return s
+}
+
+// TotalMemoryGB devolve a memória de cada nó em GB, segundo o catálogo de
+// formatos de máquina. O segundo resultado é falso quando o formato não está
+// no catálogo e a memória foi estimada a partir do número de vCPUs.
+func (s InstanceSnapshot) TotalMemoryGB() (float64, bool, error) {
+	if s.CPUCount == 0 {
+		return 0, false, fmt.Errorf("instance %s has no machine configuration", s.Name)
+	}
+	memory, known := MachineMemoryGB(s.CPUCount)
+	return memory, known, nil
+}
+
+// MaxConnections devolve a flag de banco max_connections da instância, ou 0
+// quando a flag não está definida explicitamente
+func (s InstanceSnapshot) MaxConnections() (int, error) {
+	if s.instance == nil {
+		return 0, nil
+	}
+	value, ok := s.instance.DatabaseFlags["max_connections"]
+	if !ok || value == "" {
+		return 0, nil
+	}
+	maxConnections, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid max_connections flag %q: %w", value, err)
+	}
+	return maxConnections, nil
+}
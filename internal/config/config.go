@@ -1,10 +1,14 @@
 package config
 
 import (
+	"context"
+	"crypto/sha256"
+	"errors"
 	"fmt"
 	"os"
-	"strconv"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/heraque/alloydb-autoscaler/internal/log"
 	"github.com/joho/godotenv"
@@ -13,41 +17,156 @@ import (
 // Config armazena todas as configurações do aplicativo
 type Config struct {
 	GoogleApplicationCredentials string
-	MemoryMetric                 string
-	CPUMetric                    string
-	CPUThreshold                 float64
-	MemoryThreshold              float64
-	CheckInterval                int
-	Evaluation                   int
-	GCPProject                   string
-	ClusterName                  string
-	InstanceName                 string
-	Region                       string
-	MinReplicas                  int
-	MaxReplicas                  int
 	TimeoutSeconds               int
 	LogLevel                     string
+	DryRunLog                    string
+	HTTPAddr                     string
+	LivenessMultiplier           float64
+	ReadinessMultiplier          float64
+	StateFile                    string
+	ShutdownGraceSeconds         int
+	OperationMaxWaitSeconds      int
+	LeaderElection               LeaderElection
+	Targets                      []Target
 }
 
-var (
-	cfg  Config
-	once sync.Once
+// LeaderElection configura a eleição de líder entre réplicas do autoscaler.
+// Com Backend vazio a eleição fica desativada e o processo é sempre o líder.
+type LeaderElection struct {
+	// Backend é "file", "kubernetes" ou "gcs"
+	Backend      string
+	Identity     string
+	LockFile     string
+	LeaseName    string
+	Namespace    string
+	Bucket       string
+	LeaseSeconds int
+	RenewSeconds int
+}
+
+// Escopos de cooldown
+const (
+	// CooldownOpposite suprime apenas a ação contrária à última escala
+	CooldownOpposite = "opposite"
+	// CooldownAll suprime qualquer ação de escala
+	CooldownAll = "all"
 )
 
-func init() {
-	if err := Load(); err != nil {
-		log.Fatal().Err(err).Msg("Falha ao carregar configuração")
-	}
+// Políticas de escala
+const (
+	// PolicyStep acumula votos na janela de avaliação e aplica ScaleStep
+	PolicyStep = "step"
+	// PolicyTargetTracking calcula o número de nós que leva cada regra ao seu
+	// limite e aplica esse valor em uma única alteração
+	PolicyTargetTracking = "target_tracking"
+)
+
+// Target armazena as configurações de uma instância de read pool gerenciada
+type Target struct {
+	Name            string
+	GCPProject      string
+	ClusterName     string
+	InstanceName    string
+	Region          string
+	CPUThreshold    float64
+	MemoryThreshold float64
+	CheckInterval   int
+	Evaluation      int
+	MinReplicas     int
+	MaxReplicas     int
+	DryRun          bool
+	ScaleStep       StepPolicy
+
+	// ScalingPolicy é PolicyStep ou PolicyTargetTracking. Em target tracking,
+	// desvios de até TargetTrackingTolerance por cento do limite são ignorados.
+	ScalingPolicy           string
+	TargetTrackingTolerance float64
+
+	// Limites para reduzir; abaixo do limite de aumento formam uma faixa de
+	// histerese. 0 usa o mesmo limite de aumento.
+	CPUScaleDownThreshold        float64
+	MemoryScaleDownThreshold     float64
+	ConnectionScaleDownThreshold float64
+
+	// MemoryTotalGB substitui a memória de cada nó usada no percentual de
+	// memória; 0 usa MemoryTotalMetric ou o catálogo de formatos de máquina
+	MemoryTotalGB float64
+	// MemoryTotalMetric é uma métrica opcional com a memória total de cada nó,
+	// em bytes
+	MemoryTotalMetric string
+
+	// ConnectionThreshold é o limite de uso de conexões, em percentual de
+	// max_connections; 0 desativa a verificação de conexões
+	ConnectionThreshold float64
+	// NodeAggregation combina os valores de cada nó nas regras de CPU e
+	// memória, e ConnectionAggregation na regra de conexões
+	NodeAggregation       string
+	ConnectionAggregation string
+	// MaxConnections substitui a flag max_connections da instância; 0 usa a flag
+	MaxConnections int
+
+	// ScaleUpCooldown e ScaleDownCooldown são os períodos, em segundos, após a
+	// conclusão de um aumento ou de uma redução em que novas ações são
+	// suprimidas; CooldownScope define quais: CooldownOpposite ou CooldownAll
+	ScaleUpCooldown   int
+	ScaleDownCooldown int
+	CooldownScope     string
+
+	// Timezone é o fuso das expressões cron de Schedules; vazio usa o fuso local
+	Timezone  string
+	Schedules []Schedule
+
+	Predictive Predictive
+
+	// Rules são as regras avaliadas a cada verificação. Sem regras no arquivo
+	// de configuração, são montadas a partir dos limites de CPU, memória e conexões.
+	Rules []Rule
+}
+
+// InstancePath retorna o nome completo da instância no formato GCP
+func (t Target) InstancePath() string {
+	return fmt.Sprintf("%s/instances/%s", t.ClusterPath(), t.InstanceName)
+}
+
+// ClusterPath retorna o nome completo do cluster no formato GCP
+func (t Target) ClusterPath() string {
+	return fmt.Sprintf("projects/%s/locations/%s/clusters/%s", t.GCPProject, t.Region, t.ClusterName)
+}
 
-	log.Initialize()
+// Target retorna o alvo com o nome informado
+func (c Config) Target(name string) (Target, bool) {
+	for _, t := range c.Targets {
+		if t.Name == name {
+			return t, true
+		}
+	}
+	return Target{}, false
 }
 
+// defaultReloadInterval é o intervalo padrão de verificação do arquivo de configuração
+const defaultReloadInterval = 10 * time.Second
+
+var (
+	current atomic.Pointer[Config]
+	once    sync.Once
+
+	// fileHash guarda o hash do último arquivo de configuração aplicado
+	fileHash [sha256.Size]byte
+
+	subscribersMu sync.Mutex
+	subscribers   []func(previous, updated Config)
+)
+
 // Get retorna a configuração atual
 func Get() Config {
-	return cfg
+	if c := current.Load(); c != nil {
+		return *c
+	}
+	return Config{}
 }
 
-// Load carrega as configurações do ambiente
+// Load carrega as configurações do arquivo indicado em CONFIG_FILE ou, na
+// ausência dele, das variáveis de ambiente
 func Load() error {
 	var err error
 	once.Do(func() {
@@ -56,97 +175,215 @@ func Load() error {
 	return err
 }
 
+// Set substitui a configuração atual sem validá-la nem notificar os
+// assinantes de OnChange. Usado em testes.
+func Set(c Config) {
+	current.Store(&c)
+}
+
+// FilePath retorna o caminho do arquivo de configuração, ou vazio quando a
+// configuração vem apenas de variáveis de ambiente
+func FilePath() string {
+	return os.Getenv("CONFIG_FILE")
+}
+
+// OnChange registra uma função chamada após cada recarga bem-sucedida
+func OnChange(fn func(previous, updated Config)) {
+	subscribersMu.Lock()
+	defer subscribersMu.Unlock()
+	subscribers = append(subscribers, fn)
+}
+
 func loadConfig() error {
 	// Tenta carregar o .env, mas não falha se não existir
 	_ = godotenv.Load("/app/.env")
 
-	var err error
-	cfg = Config{
-		GoogleApplicationCredentials: os.Getenv("GOOGLE_APPLICATION_CREDENTIALS"),
-		MemoryMetric:                 "alloydb.googleapis.com/instance/memory/min_available_memory",
-		CPUMetric:                    "alloydb.googleapis.com/instance/cpu/average_utilization",
-		GCPProject:                   os.Getenv("GCP_PROJECT"),
-		ClusterName:                  os.Getenv("CLUSTER_NAME"),
-		InstanceName:                 os.Getenv("INSTANCE_NAME"),
-		Region:                       os.Getenv("REGION"),
-		LogLevel:                     os.Getenv("LOG_LEVEL"),
-	}
-
-	cfg.CPUThreshold, err = parseFloatConfig("CPU_THRESHOLD")
+	var (
+		c   Config
+		err error
+	)
+	if path := FilePath(); path != "" {
+		var data []byte
+		data, err = os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("erro ao ler o arquivo de configuração %s: %w", path, err)
+		}
+		c, err = parseFile(path, data)
+		fileHash = sha256.Sum256(data)
+	} else {
+		// A validação roda mesmo com erros de leitura, para que todos os
+		// problemas sejam reportados de uma vez
+		c, err = loadEnv()
+		err = errors.Join(err, validate(c))
+	}
 	if err != nil {
 		return err
 	}
 
-	cfg.MemoryThreshold, err = parseFloatConfig("MEMORY_THRESHOLD")
-	if err != nil {
-		return err
+	current.Store(&c)
+	logLoaded(c)
+	return nil
+}
+
+// Reload relê o arquivo de configuração e substitui atomicamente a configuração
+// atual. Retorna false quando o conteúdo não mudou. Em caso de erro a
+// configuração anterior é mantida.
+func Reload() (bool, error) {
+	path := FilePath()
+	if path == "" {
+		return false, nil
 	}
 
-	cfg.CheckInterval, err = parseIntConfig("CHECK_INTERVAL")
+	data, err := os.ReadFile(path)
 	if err != nil {
-		return err
+		return false, fmt.Errorf("erro ao ler o arquivo de configuração %s: %w", path, err)
+	}
+	hash := sha256.Sum256(data)
+	if hash == fileHash {
+		return false, nil
 	}
 
-	cfg.Evaluation, err = parseIntConfig("EVALUATION")
+	c, err := parseFile(path, data)
 	if err != nil {
-		return err
+		// Evita repetir o mesmo erro a cada verificação até o arquivo mudar novamente
+		fileHash = hash
+		return false, err
 	}
+	fileHash = hash
 
-	cfg.MinReplicas, err = parseIntConfig("MIN_REPLICAS")
-	if err != nil {
-		return err
+	old := Get()
+	current.Store(&c)
+	logLoaded(c)
+
+	if c.LogLevel != "" && c.LogLevel != old.LogLevel {
+		log.SetLevel(c.LogLevel)
 	}
-	if cfg.MinReplicas < 1 {
-		return fmt.Errorf("MIN_REPLICAS deve ser pelo menos 1, valor atual: %d", cfg.MinReplicas)
+	if c.GoogleApplicationCredentials != old.GoogleApplicationCredentials {
+		log.Warn().
+			Str("component", "config").
+			Str("action", "reload").
+			Msg("googleApplicationCredentials alterado; a mudança só terá efeito após reiniciar")
 	}
-
-	cfg.MaxReplicas, err = parseIntConfig("MAX_REPLICAS")
-	if err != nil {
-		return err
+	if c.StateFile != old.StateFile {
+		log.Warn().
+			Str("component", "config").
+			Str("action", "reload").
+			Msg("stateFile alterado; a mudança só terá efeito após reiniciar")
+	}
+	if c.LeaderElection != old.LeaderElection {
+		log.Warn().
+			Str("component", "config").
+			Str("action", "reload").
+			Msg("leaderElection alterado; a mudança só terá efeito após reiniciar")
 	}
-	if cfg.MaxReplicas > 20 {
-		return fmt.Errorf("MAX_REPLICAS não pode exceder 20, valor atual: %d", cfg.MaxReplicas)
+	if c.HTTPAddr != old.HTTPAddr {
+		log.Warn().
+			Str("component", "config").
+			Str("action", "reload").
+			Msg("httpAddr alterado; a mudança só terá efeito após reiniciar")
 	}
 
-	if cfg.MinReplicas > cfg.MaxReplicas {
-		return fmt.Errorf("MIN_REPLICAS (%d) não pode ser maior que MAX_REPLICAS (%d)", cfg.MinReplicas, cfg.MaxReplicas)
+	subscribersMu.Lock()
+	fns := make([]func(previous, updated Config), len(subscribers))
+	copy(fns, subscribers)
+	subscribersMu.Unlock()
+	for _, fn := range fns {
+		fn(old, c)
 	}
 
-	cfg.TimeoutSeconds, err = parseIntConfig("TIMEOUT_SECONDS")
-	if err != nil {
-		return err
+	return true, nil
+}
+
+// Watch verifica periodicamente o arquivo de configuração e o recarrega quando
+// o conteúdo muda (inclusive quando um ConfigMap montado troca o link
+// simbólico). O intervalo vem de CONFIG_RELOAD_INTERVAL, em segundos.
+func Watch(ctx context.Context) {
+	if FilePath() == "" {
+		return
 	}
-	if cfg.TimeoutSeconds <= 0 {
-		return fmt.Errorf("TIMEOUT_SECONDS deve ser maior que 0, valor atual: %d", cfg.TimeoutSeconds)
+
+	interval := defaultReloadInterval
+	if value := os.Getenv("CONFIG_RELOAD_INTERVAL"); value != "" {
+		seconds, err := parseIntValue("CONFIG_RELOAD_INTERVAL", value)
+		if err != nil || seconds <= 0 {
+			log.Warn().
+				Str("component", "config").
+				Str("action", "watch").
+				Str("value", value).
+				Msg("CONFIG_RELOAD_INTERVAL inválido, usando o padrão")
+		} else {
+			interval = time.Duration(seconds) * time.Second
+		}
 	}
 
-	log.Debug().
-		Float64("CPUThreshold", cfg.CPUThreshold).
-		Float64("MemoryThreshold", cfg.MemoryThreshold).
-		Int("CheckInterval", cfg.CheckInterval).
-		Int("Evaluation", cfg.Evaluation).
-		Int("MinReplicas", cfg.MinReplicas).
-		Int("MaxReplicas", cfg.MaxReplicas).
-		Int("TimeoutSeconds", cfg.TimeoutSeconds).
-		Msg("Configuração carregada com sucesso")
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
 
-	return nil
+		changed, err := Reload()
+		if err != nil {
+			log.Error(err).
+				Str("component", "config").
+				Str("action", "reload").
+				Str("path", FilePath()).
+				Msg("Configuração inválida, mantendo a configuração anterior")
+			continue
+		}
+		if changed {
+			log.Info().
+				Str("component", "config").
+				Str("action", "reload").
+				Str("path", FilePath()).
+				Int("targets", len(Get().Targets)).
+				Msg("Configuração recarregada")
+		}
+	}
 }
 
-func parseFloatConfig(key string) (float64, error) {
-	value := os.Getenv(key)
-	parsed, err := strconv.ParseFloat(value, 64)
-	if err != nil {
-		return 0, fmt.Errorf("revise %s: o valor '%s' é inválido. Certifique-se de que o valor seja um número válido sem letras ou caracteres especiais", key, value)
+func logLoaded(c Config) {
+	for _, t := range c.Targets {
+		log.Debug().
+			Str("target", t.Name).
+			Str("instance", t.InstanceName).
+			Str("cluster", t.ClusterName).
+			Float64("CPUThreshold", t.CPUThreshold).
+			Float64("MemoryThreshold", t.MemoryThreshold).
+			Int("CheckInterval", t.CheckInterval).
+			Int("Evaluation", t.Evaluation).
+			Int("MinReplicas", t.MinReplicas).
+			Int("MaxReplicas", t.MaxReplicas).
+			Bool("DryRun", t.DryRun).
+			Str("ScaleStep", t.ScaleStep.String()).
+			Str("ScalingPolicy", t.ScalingPolicy).
+			Float64("TargetTrackingTolerance", t.TargetTrackingTolerance).
+			Int("ScaleUpCooldown", t.ScaleUpCooldown).
+			Int("ScaleDownCooldown", t.ScaleDownCooldown).
+			Str("CooldownScope", t.CooldownScope).
+			Float64("MemoryTotalGB", t.MemoryTotalGB).
+			Str("MemoryTotalMetric", t.MemoryTotalMetric).
+			Float64("ConnectionThreshold", t.ConnectionThreshold).
+			Str("NodeAggregation", t.NodeAggregation).
+			Strs("Rules", ruleNames(t.Rules)).
+			Int("Schedules", len(t.Schedules)).
+			Bool("Predictive", t.Predictive.Enabled).
+			Msg("Alvo configurado")
 	}
-	return parsed, nil
+
+	log.Debug().
+		Int("TimeoutSeconds", c.TimeoutSeconds).
+		Int("Targets", len(c.Targets)).
+		Msg("Configuração carregada com sucesso")
 }
 
-func parseIntConfig(key string) (int, error) {
-	value := os.Getenv(key)
-	parsed, err := strconv.Atoi(value)
-	if err != nil {
-		return 0, fmt.Errorf("revise %s: o valor '%s' é inválido. Certifique-se de que o valor seja um número inteiro válido sem letras ou caracteres especiais", key, value)
+func ruleNames(rules []Rule) []string {
+	names := make([]string, len(rules))
+	for i, r := range rules {
+		names[i] = r.Name
 	}
-	return parsed, nil
+	return names
 }
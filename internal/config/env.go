@@ -0,0 +1,308 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+)
+
+// loadEnv monta a configuração a partir das variáveis de ambiente
+func loadEnv() (Config, error) {
+	var errs []error
+	c := Config{
+		GoogleApplicationCredentials: os.Getenv("GOOGLE_APPLICATION_CREDENTIALS"),
+		LogLevel:                     os.Getenv("LOG_LEVEL"),
+		DryRunLog:                    os.Getenv("DRY_RUN_LOG"),
+		HTTPAddr:                     defaultHTTPAddr,
+		StateFile:                    os.Getenv("STATE_FILE"),
+	}
+	if value, ok := os.LookupEnv("HTTP_ADDR"); ok {
+		c.HTTPAddr = value
+	}
+
+	var err error
+	c.TimeoutSeconds, err = parseIntConfig("TIMEOUT_SECONDS")
+	errs = append(errs, err)
+
+	c.LivenessMultiplier, err = parseOptionalFloat("HEALTH_LIVENESS_MULTIPLIER", defaultHealthMultiplier)
+	errs = append(errs, err)
+
+	c.ReadinessMultiplier, err = parseOptionalFloat("HEALTH_READINESS_MULTIPLIER", defaultHealthMultiplier)
+	errs = append(errs, err)
+
+	c.ShutdownGraceSeconds, err = parseOptionalInt("SHUTDOWN_GRACE_SECONDS", defaultShutdownGraceSeconds)
+	errs = append(errs, err)
+
+	c.OperationMaxWaitSeconds, err = parseOptionalInt("OPERATION_MAX_WAIT_SECONDS", defaultOperationMaxWaitSeconds)
+	errs = append(errs, err)
+
+	c.LeaderElection, err = loadLeaderElection()
+	errs = append(errs, err)
+
+	c.Targets, err = loadTargets()
+	errs = append(errs, err)
+
+	return c, errors.Join(errs...)
+}
+
+// loadLeaderElection lê as variáveis LEADER_ELECTION_*
+func loadLeaderElection() (LeaderElection, error) {
+	le := LeaderElection{
+		Backend:   os.Getenv("LEADER_ELECTION"),
+		Identity:  os.Getenv("LEADER_ELECTION_IDENTITY"),
+		LockFile:  os.Getenv("LEADER_ELECTION_LOCK_FILE"),
+		LeaseName: defaultLeaseName,
+		Namespace: os.Getenv("LEADER_ELECTION_NAMESPACE"),
+		Bucket:    os.Getenv("LEADER_ELECTION_BUCKET"),
+	}
+	if value := os.Getenv("LEADER_ELECTION_LEASE_NAME"); value != "" {
+		le.LeaseName = value
+	}
+
+	var errs []error
+	var err error
+	le.LeaseSeconds, err = parseOptionalInt("LEADER_ELECTION_LEASE_SECONDS", defaultLeaseSeconds)
+	errs = append(errs, err)
+	le.RenewSeconds, err = parseOptionalInt("LEADER_ELECTION_RENEW_SECONDS", defaultRenewSeconds)
+	errs = append(errs, err)
+
+	return le, errors.Join(errs...)
+}
+
+// loadTargets carrega os alvos definidos como TARGET_<N>_<CHAVE>, com N a partir
+// de 1. Chaves ausentes em um alvo usam a variável global de mesmo nome. Sem
+// nenhum TARGET_1_INSTANCE_NAME, um único alvo é montado a partir das globais.
+func loadTargets() ([]Target, error) {
+	var (
+		targets []Target
+		errs    []error
+	)
+	for n := 1; ; n++ {
+		prefix := fmt.Sprintf("TARGET_%d_", n)
+		if os.Getenv(prefix+"INSTANCE_NAME") == "" {
+			break
+		}
+		t, err := loadTarget(prefix)
+		targets = append(targets, t)
+		errs = append(errs, err)
+	}
+
+	if len(targets) == 0 {
+		t, err := loadTarget("")
+		targets = append(targets, t)
+		errs = append(errs, err)
+	}
+
+	return targets, errors.Join(errs...)
+}
+
+func loadTarget(prefix string) (Target, error) {
+	lookup := func(key string) (string, string) {
+		if prefix != "" {
+			if value, ok := os.LookupEnv(prefix + key); ok {
+				return prefix + key, value
+			}
+		}
+		return key, os.Getenv(key)
+	}
+	parseFloat := func(key string) (float64, error) {
+		return parseFloatValue(lookup(key))
+	}
+	parseInt := func(key string) (int, error) {
+		return parseIntValue(lookup(key))
+	}
+	parseBool := func(key string) (bool, error) {
+		return parseBoolValue(lookup(key))
+	}
+	str := func(key string) string {
+		_, value := lookup(key)
+		return value
+	}
+	optionalFloat := func(key string) (float64, error) {
+		if key, value := lookup(key); value != "" {
+			return parseFloatValue(key, value)
+		}
+		return 0, nil
+	}
+	optionalInt := func(key string) (int, error) {
+		if key, value := lookup(key); value != "" {
+			return parseIntValue(key, value)
+		}
+		return 0, nil
+	}
+
+	var (
+		errs []error
+		err  error
+	)
+	t := Target{
+		GCPProject:   str("GCP_PROJECT"),
+		ClusterName:  str("CLUSTER_NAME"),
+		InstanceName: str("INSTANCE_NAME"),
+		Region:       str("REGION"),
+
+		MemoryTotalMetric: str("MEMORY_TOTAL_METRIC"),
+
+		ConnectionAggregation: str("CONNECTION_AGGREGATION"),
+		NodeAggregation:       str("NODE_AGGREGATION"),
+		CooldownScope:         str("COOLDOWN_SCOPE"),
+		ScalingPolicy:         str("SCALING_POLICY"),
+	}
+	if t.ConnectionAggregation == "" {
+		t.ConnectionAggregation = defaultConnectionAggregation
+	}
+	if t.NodeAggregation == "" {
+		t.NodeAggregation = defaultNodeAggregation
+	}
+	if t.CooldownScope == "" {
+		t.CooldownScope = defaultCooldownScope
+	}
+	if t.ScalingPolicy == "" {
+		t.ScalingPolicy = defaultScalingPolicy
+	}
+	if prefix != "" {
+		t.Name = os.Getenv(prefix + "NAME")
+	}
+	if t.Name == "" {
+		t.Name = defaultTargetName(t)
+	}
+
+	t.CPUThreshold, err = parseFloat("CPU_THRESHOLD")
+	errs = append(errs, err)
+
+	t.MemoryThreshold, err = parseFloat("MEMORY_THRESHOLD")
+	errs = append(errs, err)
+
+	t.CPUScaleDownThreshold, err = optionalFloat("CPU_SCALE_DOWN_THRESHOLD")
+	errs = append(errs, err)
+
+	t.MemoryScaleDownThreshold, err = optionalFloat("MEMORY_SCALE_DOWN_THRESHOLD")
+	errs = append(errs, err)
+
+	t.MemoryTotalGB, err = optionalFloat("MEMORY_TOTAL_GB")
+	errs = append(errs, err)
+
+	t.CheckInterval, err = parseInt("CHECK_INTERVAL")
+	errs = append(errs, err)
+
+	t.Evaluation, err = parseInt("EVALUATION")
+	errs = append(errs, err)
+
+	t.MinReplicas, err = parseInt("MIN_REPLICAS")
+	errs = append(errs, err)
+
+	t.MaxReplicas, err = parseInt("MAX_REPLICAS")
+	errs = append(errs, err)
+
+	t.DryRun, err = parseBool("DRY_RUN")
+	errs = append(errs, err)
+
+	t.ScaleStep, err = parseStepValue(lookup("ESCALAR_THRESHOLD"))
+	errs = append(errs, err)
+
+	t.TargetTrackingTolerance = defaultTargetTrackingTolerance
+	if _, value := lookup("TARGET_TRACKING_TOLERANCE"); value != "" {
+		t.TargetTrackingTolerance, err = optionalFloat("TARGET_TRACKING_TOLERANCE")
+		errs = append(errs, err)
+	}
+
+	t.ConnectionThreshold, err = optionalFloat("CONNECTION_THRESHOLD")
+	errs = append(errs, err)
+
+	t.ConnectionScaleDownThreshold, err = optionalFloat("CONNECTION_SCALE_DOWN_THRESHOLD")
+	errs = append(errs, err)
+
+	t.MaxConnections, err = optionalInt("MAX_CONNECTIONS")
+	errs = append(errs, err)
+
+	t.ScaleUpCooldown, err = optionalInt("SCALE_UP_COOLDOWN")
+	errs = append(errs, err)
+
+	t.ScaleDownCooldown, err = optionalInt("SCALE_DOWN_COOLDOWN")
+	errs = append(errs, err)
+
+	t.Rules = defaultRules(t)
+
+	t.Predictive = defaultPredictive()
+	t.Predictive.Rules = parseRuleList(str("PREDICTIVE_RULES"))
+	t.Predictive.Enabled, err = parseBool("PREDICTIVE_ENABLED")
+	errs = append(errs, err)
+	for _, field := range []struct {
+		key   string
+		value *int
+	}{
+		{"PREDICTIVE_HISTORY_WEEKS", &t.Predictive.HistoryWeeks},
+		{"PREDICTIVE_LOOKAHEAD_MINUTES", &t.Predictive.LookaheadMinutes},
+		{"PREDICTIVE_REFRESH_MINUTES", &t.Predictive.RefreshMinutes},
+		{"PREDICTIVE_HISTORY_TIMEOUT_SECONDS", &t.Predictive.HistoryTimeoutSeconds},
+	} {
+		if key, value := lookup(field.key); value != "" {
+			*field.value, err = parseIntValue(key, value)
+			errs = append(errs, err)
+		}
+	}
+	if value := str("PREDICTIVE_NODE_COUNT_METRIC"); value != "" {
+		t.Predictive.NodeCountMetric = value
+	}
+
+	return t, errors.Join(errs...)
+}
+
+func parseIntConfig(key string) (int, error) {
+	return parseIntValue(key, os.Getenv(key))
+}
+
+// parseOptionalFloat lê uma variável numérica opcional, usando fallback quando ausente
+func parseOptionalFloat(key string, fallback float64) (float64, error) {
+	value := os.Getenv(key)
+	if value == "" {
+		return fallback, nil
+	}
+	return parseFloatValue(key, value)
+}
+
+// parseOptionalInt lê uma variável inteira opcional, usando fallback quando ausente
+func parseOptionalInt(key string, fallback int) (int, error) {
+	value := os.Getenv(key)
+	if value == "" {
+		return fallback, nil
+	}
+	return parseIntValue(key, value)
+}
+
+func parseFloatValue(key, value string) (float64, error) {
+	parsed, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return 0, fmt.Errorf("revise %s: o valor '%s' é inválido. Certifique-se de que o valor seja um número válido sem letras ou caracteres especiais", key, value)
+	}
+	return parsed, nil
+}
+
+func parseStepValue(key, value string) (StepPolicy, error) {
+	policy, err := ParseStepPolicy(value)
+	if err != nil {
+		return StepPolicy{}, fmt.Errorf("revise %s: %w", key, err)
+	}
+	return policy, nil
+}
+
+func parseIntValue(key, value string) (int, error) {
+	parsed, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, fmt.Errorf("revise %s: o valor '%s' é inválido. Certifique-se de que o valor seja um número inteiro válido sem letras ou caracteres especiais", key, value)
+	}
+	return parsed, nil
+}
+
+// parseBoolValue interpreta valores booleanos; ausente equivale a false
+func parseBoolValue(key, value string) (bool, error) {
+	if value == "" {
+		return false, nil
+	}
+	parsed, err := strconv.ParseBool(value)
+	if err != nil {
+		return false, fmt.Errorf("revise %s: o valor '%s' é inválido. Use true ou false", key, value)
+	}
+	return parsed, nil
+}
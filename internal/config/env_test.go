@@ -0,0 +1,37 @@
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestLoadConfigEnvReportsAllErrors(t *testing.T) {
+	env := map[string]string{
+		"CONFIG_FILE":      "",
+		"TIMEOUT_SECONDS":  "thirty",
+		"GCP_PROJECT":      "project",
+		"REGION":           "us-central1",
+		"CLUSTER_NAME":     "cluster",
+		"INSTANCE_NAME":    "read-pool",
+		"CPU_THRESHOLD":    "70",
+		"MEMORY_THRESHOLD": "80",
+		"CHECK_INTERVAL":   "60",
+		"EVALUATION":       "300",
+		"MIN_REPLICAS":     "4",
+		"MAX_REPLICAS":     "2",
+	}
+	for key, value := range env {
+		t.Setenv(key, value)
+	}
+
+	err := loadConfig()
+	if err == nil {
+		t.Fatal("loadConfig() error = nil")
+	}
+	// O erro de leitura não impede a validação do restante da configuração
+	for _, want := range []string{"TIMEOUT_SECONDS: o valor 'thirty' é inválido", "MIN_REPLICAS (4) não pode ser maior que MAX_REPLICAS (2)"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("loadConfig() error = %v, want it to contain %q", err, want)
+		}
+	}
+}
@@ -0,0 +1,434 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// fileVersion é a versão do formato de arquivo suportada
+const fileVersion = 1
+
+var (
+	// defaultHTTPAddr é o endereço padrão do servidor HTTP de métricas e
+	// health checks; vazio desativa o servidor
+	defaultHTTPAddr = ":8080"
+
+	// defaultHealthMultiplier é quantos CheckInterval os health checks toleram
+	// sem progresso do loop ou sem coleta de métricas bem-sucedida
+	defaultHealthMultiplier = 3.0
+
+	// defaultShutdownGraceSeconds é quanto tempo uma operação de escala em
+	// andamento pode continuar após SIGTERM; cabe no padrão de 30s do Kubernetes
+	defaultShutdownGraceSeconds = 25
+
+	// defaultOperationMaxWaitSeconds é quanto tempo uma operação de escala é
+	// aguardada antes de ser considerada travada
+	defaultOperationMaxWaitSeconds = 1800
+
+	// defaultLeaseSeconds é por quanto tempo a liderança vale sem renovação;
+	// defaultRenewSeconds é o intervalo entre renovações
+	defaultLeaseSeconds = 15
+	defaultRenewSeconds = 5
+)
+
+// defaultLeaseName é o nome do Lease do Kubernetes ou do objeto no GCS
+const defaultLeaseName = "alloydb-autoscaler-leader"
+
+const (
+	defaultMemoryMetric     = "alloydb.googleapis.com/instance/memory/min_available_memory"
+	defaultCPUMetric        = "alloydb.googleapis.com/instance/cpu/average_utilization"
+	defaultConnectionMetric = "alloydb.googleapis.com/instance/postgres/total_connections"
+
+	// defaultConnectionAggregation considera o nó mais carregado, já que cada
+	// nó do read pool tem seu próprio limite de conexões
+	defaultConnectionAggregation = AggregationMax
+	defaultNodeAggregation       = AggregationMax
+
+	defaultCooldownScope = CooldownOpposite
+
+	defaultScalingPolicy = PolicyStep
+	// defaultTargetTrackingTolerance segue a tolerância padrão do HPA do Kubernetes
+	defaultTargetTrackingTolerance = 10.0
+)
+
+// fileConfig é o formato do arquivo de configuração (YAML ou JSON)
+type fileConfig struct {
+	Version                      int          `yaml:"version" json:"version"`
+	GoogleApplicationCredentials string       `yaml:"googleApplicationCredentials" json:"googleApplicationCredentials"`
+	LogLevel                     string       `yaml:"logLevel" json:"logLevel"`
+	TimeoutSeconds               int          `yaml:"timeoutSeconds" json:"timeoutSeconds"`
+	DryRunLog                    string       `yaml:"dryRunLog" json:"dryRunLog"`
+	HTTPAddr                     *string      `yaml:"httpAddr" json:"httpAddr"`
+	Health                       fileHealth   `yaml:"health" json:"health"`
+	StateFile                    string       `yaml:"stateFile" json:"stateFile"`
+	ShutdownGraceSeconds         *int         `yaml:"shutdownGraceSeconds" json:"shutdownGraceSeconds"`
+	OperationMaxWaitSeconds      *int         `yaml:"operationMaxWaitSeconds" json:"operationMaxWaitSeconds"`
+	LeaderElection               fileLeader   `yaml:"leaderElection" json:"leaderElection"`
+	Defaults                     targetFields `yaml:"defaults" json:"defaults"`
+	Targets                      []fileTarget `yaml:"targets" json:"targets"`
+}
+
+// fileHealth configura os endpoints /healthz e /readyz
+type fileHealth struct {
+	LivenessMultiplier  *float64 `yaml:"livenessMultiplier" json:"livenessMultiplier"`
+	ReadinessMultiplier *float64 `yaml:"readinessMultiplier" json:"readinessMultiplier"`
+}
+
+// fileLeader configura a eleição de líder entre réplicas
+type fileLeader struct {
+	Backend      string  `yaml:"backend" json:"backend"`
+	Identity     string  `yaml:"identity" json:"identity"`
+	LockFile     string  `yaml:"lockFile" json:"lockFile"`
+	LeaseName    *string `yaml:"leaseName" json:"leaseName"`
+	Namespace    string  `yaml:"namespace" json:"namespace"`
+	Bucket       string  `yaml:"bucket" json:"bucket"`
+	LeaseSeconds *int    `yaml:"leaseSeconds" json:"leaseSeconds"`
+	RenewSeconds *int    `yaml:"renewSeconds" json:"renewSeconds"`
+}
+
+// targetFields contém os campos de um alvo. Campos nulos herdam o valor de
+// defaults.
+type targetFields struct {
+	GCPProject      *string  `yaml:"gcpProject" json:"gcpProject"`
+	Region          *string  `yaml:"region" json:"region"`
+	ClusterName     *string  `yaml:"clusterName" json:"clusterName"`
+	InstanceName    *string  `yaml:"instanceName" json:"instanceName"`
+	CPUThreshold    *float64 `yaml:"cpuThreshold" json:"cpuThreshold"`
+	MemoryThreshold *float64 `yaml:"memoryThreshold" json:"memoryThreshold"`
+	CheckInterval   *int     `yaml:"checkInterval" json:"checkInterval"`
+
+	CPUScaleDownThreshold        *float64 `yaml:"cpuScaleDownThreshold" json:"cpuScaleDownThreshold"`
+	MemoryScaleDownThreshold     *float64 `yaml:"memoryScaleDownThreshold" json:"memoryScaleDownThreshold"`
+	ConnectionScaleDownThreshold *float64 `yaml:"connectionScaleDownThreshold" json:"connectionScaleDownThreshold"`
+
+	Evaluation  *int    `yaml:"evaluation" json:"evaluation"`
+	MinReplicas *int    `yaml:"minReplicas" json:"minReplicas"`
+	MaxReplicas *int    `yaml:"maxReplicas" json:"maxReplicas"`
+	DryRun      *bool   `yaml:"dryRun" json:"dryRun"`
+	ScaleStep   *string `yaml:"scaleStep" json:"scaleStep"`
+
+	ScalingPolicy           *string  `yaml:"scalingPolicy" json:"scalingPolicy"`
+	TargetTrackingTolerance *float64 `yaml:"targetTrackingTolerance" json:"targetTrackingTolerance"`
+
+	MemoryTotalGB     *float64 `yaml:"memoryTotalGB" json:"memoryTotalGB"`
+	MemoryTotalMetric *string  `yaml:"memoryTotalMetric" json:"memoryTotalMetric"`
+
+	ConnectionThreshold   *float64 `yaml:"connectionThreshold" json:"connectionThreshold"`
+	ConnectionAggregation *string  `yaml:"connectionAggregation" json:"connectionAggregation"`
+	NodeAggregation       *string  `yaml:"nodeAggregation" json:"nodeAggregation"`
+	MaxConnections        *int     `yaml:"maxConnections" json:"maxConnections"`
+
+	ScaleUpCooldown   *int    `yaml:"scaleUpCooldown" json:"scaleUpCooldown"`
+	ScaleDownCooldown *int    `yaml:"scaleDownCooldown" json:"scaleDownCooldown"`
+	CooldownScope     *string `yaml:"cooldownScope" json:"cooldownScope"`
+
+	Timezone  *string         `yaml:"timezone" json:"timezone"`
+	Schedules *[]fileSchedule `yaml:"schedules" json:"schedules"`
+
+	Predictive *filePredictive `yaml:"predictive" json:"predictive"`
+
+	Rules *[]fileRule `yaml:"rules" json:"rules"`
+}
+
+// filePredictive é a configuração de escala preditiva no arquivo
+type filePredictive struct {
+	Enabled          bool     `yaml:"enabled" json:"enabled"`
+	Rules            []string `yaml:"rules" json:"rules"`
+	HistoryWeeks     *int     `yaml:"historyWeeks" json:"historyWeeks"`
+	LookaheadMinutes *int     `yaml:"lookaheadMinutes" json:"lookaheadMinutes"`
+	RefreshMinutes   *int     `yaml:"refreshMinutes" json:"refreshMinutes"`
+	NodeCountMetric  *string  `yaml:"nodeCountMetric" json:"nodeCountMetric"`
+
+	HistoryTimeoutSeconds *int `yaml:"historyTimeoutSeconds" json:"historyTimeoutSeconds"`
+}
+
+// resolve aplica os valores padrão da escala preditiva
+func (fp *filePredictive) resolve() Predictive {
+	p := defaultPredictive()
+	if fp == nil {
+		return p
+	}
+	p.Enabled = fp.Enabled
+	p.Rules = fp.Rules
+	p.HistoryWeeks = pick(fp.HistoryWeeks, &p.HistoryWeeks)
+	p.LookaheadMinutes = pick(fp.LookaheadMinutes, &p.LookaheadMinutes)
+	p.RefreshMinutes = pick(fp.RefreshMinutes, &p.RefreshMinutes)
+	p.NodeCountMetric = pick(fp.NodeCountMetric, &p.NodeCountMetric)
+	p.HistoryTimeoutSeconds = pick(fp.HistoryTimeoutSeconds, &p.HistoryTimeoutSeconds)
+	return p
+}
+
+// fileSchedule é um agendamento no arquivo de configuração
+type fileSchedule struct {
+	Name        string `yaml:"name" json:"name"`
+	Start       string `yaml:"start" json:"start"`
+	Duration    string `yaml:"duration" json:"duration"`
+	MinReplicas int    `yaml:"minReplicas" json:"minReplicas"`
+	MaxReplicas int    `yaml:"maxReplicas" json:"maxReplicas"`
+	Replicas    int    `yaml:"replicas" json:"replicas"`
+}
+
+// resolve interpreta a duração e a expressão cron no fuso loc
+func (fs fileSchedule) resolve(loc *time.Location) (Schedule, error) {
+	duration, err := time.ParseDuration(fs.Duration)
+	if err != nil {
+		return Schedule{}, fmt.Errorf("agendamento '%s': duration '%s' inválida: use, por exemplo, 11h ou 90m", fs.Name, fs.Duration)
+	}
+	return parseSchedule(Schedule{
+		Name:        fs.Name,
+		Start:       fs.Start,
+		Duration:    duration,
+		MinReplicas: fs.MinReplicas,
+		MaxReplicas: fs.MaxReplicas,
+		Replicas:    fs.Replicas,
+	}, loc)
+}
+
+// fileRule é uma regra de escala no arquivo de configuração
+type fileRule struct {
+	Name       string   `yaml:"name" json:"name"`
+	Metric     string   `yaml:"metric" json:"metric"`
+	Aligner    string   `yaml:"aligner" json:"aligner"`
+	Reducer    string   `yaml:"reducer" json:"reducer"`
+	Conversion string   `yaml:"conversion" json:"conversion"`
+	Scale      *float64 `yaml:"scale" json:"scale"`
+	Threshold  *float64 `yaml:"threshold" json:"threshold"`
+	// ScaleDownThreshold é opcional; sem ele a regra reduz abaixo de threshold
+	ScaleDownThreshold *float64 `yaml:"scaleDownThreshold" json:"scaleDownThreshold"`
+	Direction          string   `yaml:"direction" json:"direction"`
+	Weight             *float64 `yaml:"weight" json:"weight"`
+	// NodeAggregation é opcional; sem ela a regra usa o nodeAggregation do alvo
+	NodeAggregation string `yaml:"nodeAggregation" json:"nodeAggregation"`
+}
+
+// resolve aplica os valores padrão de uma regra; nodeAggregation é a
+// agregação entre nós do alvo
+func (fr fileRule) resolve(nodeAggregation string) (Rule, error) {
+	if fr.Threshold == nil {
+		return Rule{}, fmt.Errorf("regra '%s': threshold é obrigatório", fr.Name)
+	}
+	one := 1.0
+	r := Rule{
+		Name:            fr.Name,
+		Metric:          fr.Metric,
+		Aligner:         fr.Aligner,
+		Reducer:         fr.Reducer,
+		NodeAggregation: fr.NodeAggregation,
+		Conversion:      fr.Conversion,
+		Scale:           pick(fr.Scale, &one),
+		Threshold:       *fr.Threshold,
+		Direction:       fr.Direction,
+		Weight:          pick(fr.Weight, &one),
+
+		ScaleDownThreshold: pick(fr.ScaleDownThreshold, fr.Threshold),
+	}
+	if r.Conversion == "" {
+		r.Conversion = ConversionNone
+	}
+	if r.Direction == "" {
+		r.Direction = DirectionBoth
+	}
+	if r.NodeAggregation == "" {
+		r.NodeAggregation = nodeAggregation
+	}
+	return r, nil
+}
+
+type fileTarget struct {
+	Name         string `yaml:"name" json:"name"`
+	targetFields `yaml:",inline"`
+}
+
+// parseFile decodifica o arquivo de configuração conforme a extensão (.json ou
+// YAML) e valida o resultado. Erros nos alvos não impedem a validação: os
+// problemas encontrados em ambas são reportados de uma vez.
+func parseFile(path string, data []byte) (Config, error) {
+	var fc fileConfig
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		dec := json.NewDecoder(bytes.NewReader(data))
+		dec.DisallowUnknownFields()
+		if err := dec.Decode(&fc); err != nil {
+			return Config{}, fmt.Errorf("erro ao interpretar %s: %w", path, err)
+		}
+	} else {
+		dec := yaml.NewDecoder(bytes.NewReader(data))
+		dec.KnownFields(true)
+		if err := dec.Decode(&fc); err != nil {
+			return Config{}, fmt.Errorf("erro ao interpretar %s: %w", path, err)
+		}
+	}
+
+	if fc.Version != fileVersion {
+		return Config{}, fmt.Errorf("%s: versão %d não suportada, use version: %d", path, fc.Version, fileVersion)
+	}
+
+	c := Config{
+		GoogleApplicationCredentials: fc.GoogleApplicationCredentials,
+		LogLevel:                     fc.LogLevel,
+		TimeoutSeconds:               fc.TimeoutSeconds,
+		DryRunLog:                    fc.DryRunLog,
+		HTTPAddr:                     pick(fc.HTTPAddr, &defaultHTTPAddr),
+		LivenessMultiplier:           pick(fc.Health.LivenessMultiplier, &defaultHealthMultiplier),
+		ReadinessMultiplier:          pick(fc.Health.ReadinessMultiplier, &defaultHealthMultiplier),
+		StateFile:                    fc.StateFile,
+		ShutdownGraceSeconds:         pick(fc.ShutdownGraceSeconds, &defaultShutdownGraceSeconds),
+		OperationMaxWaitSeconds:      pick(fc.OperationMaxWaitSeconds, &defaultOperationMaxWaitSeconds),
+		LeaderElection:               fc.LeaderElection.resolve(),
+	}
+	if c.GoogleApplicationCredentials == "" {
+		c.GoogleApplicationCredentials = os.Getenv("GOOGLE_APPLICATION_CREDENTIALS")
+	}
+
+	var errs []error
+	for i, ft := range fc.Targets {
+		t, err := ft.resolve(fc.Defaults)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("targets[%d]: %w", i, err))
+		}
+		c.Targets = append(c.Targets, t)
+	}
+
+	errs = append(errs, validate(c))
+	return c, errors.Join(errs...)
+}
+
+// resolve aplica os valores padrão da eleição de líder
+func (fl fileLeader) resolve() LeaderElection {
+	leaseName := defaultLeaseName
+	return LeaderElection{
+		Backend:      fl.Backend,
+		Identity:     fl.Identity,
+		LockFile:     fl.LockFile,
+		LeaseName:    pick(fl.LeaseName, &leaseName),
+		Namespace:    fl.Namespace,
+		Bucket:       fl.Bucket,
+		LeaseSeconds: pick(fl.LeaseSeconds, &defaultLeaseSeconds),
+		RenewSeconds: pick(fl.RenewSeconds, &defaultRenewSeconds),
+	}
+}
+
+// resolve aplica os valores padrão aos campos não definidos no alvo
+func (ft fileTarget) resolve(defaults targetFields) (Target, error) {
+	t := Target{
+		Name:            ft.Name,
+		GCPProject:      pick(ft.GCPProject, defaults.GCPProject),
+		Region:          pick(ft.Region, defaults.Region),
+		ClusterName:     pick(ft.ClusterName, defaults.ClusterName),
+		InstanceName:    pick(ft.InstanceName, defaults.InstanceName),
+		CPUThreshold:    pick(ft.CPUThreshold, defaults.CPUThreshold),
+		MemoryThreshold: pick(ft.MemoryThreshold, defaults.MemoryThreshold),
+		CheckInterval:   pick(ft.CheckInterval, defaults.CheckInterval),
+		Evaluation:      pick(ft.Evaluation, defaults.Evaluation),
+		MinReplicas:     pick(ft.MinReplicas, defaults.MinReplicas),
+		MaxReplicas:     pick(ft.MaxReplicas, defaults.MaxReplicas),
+		DryRun:          pick(ft.DryRun, defaults.DryRun),
+
+		CPUScaleDownThreshold:        pick(ft.CPUScaleDownThreshold, defaults.CPUScaleDownThreshold),
+		MemoryScaleDownThreshold:     pick(ft.MemoryScaleDownThreshold, defaults.MemoryScaleDownThreshold),
+		ConnectionScaleDownThreshold: pick(ft.ConnectionScaleDownThreshold, defaults.ConnectionScaleDownThreshold),
+
+		MemoryTotalGB:     pick(ft.MemoryTotalGB, defaults.MemoryTotalGB),
+		MemoryTotalMetric: pick(ft.MemoryTotalMetric, defaults.MemoryTotalMetric),
+
+		ConnectionThreshold:   pick(ft.ConnectionThreshold, defaults.ConnectionThreshold),
+		ConnectionAggregation: pick(ft.ConnectionAggregation, defaults.ConnectionAggregation),
+		NodeAggregation:       pick(ft.NodeAggregation, defaults.NodeAggregation),
+		MaxConnections:        pick(ft.MaxConnections, defaults.MaxConnections),
+
+		ScaleUpCooldown:   pick(ft.ScaleUpCooldown, defaults.ScaleUpCooldown),
+		ScaleDownCooldown: pick(ft.ScaleDownCooldown, defaults.ScaleDownCooldown),
+		CooldownScope:     pick(ft.CooldownScope, defaults.CooldownScope),
+
+		ScalingPolicy:           pick(ft.ScalingPolicy, defaults.ScalingPolicy),
+		TargetTrackingTolerance: pick(ft.TargetTrackingTolerance, defaults.TargetTrackingTolerance),
+
+		Timezone: pick(ft.Timezone, defaults.Timezone),
+	}
+	predictive := ft.Predictive
+	if predictive == nil {
+		predictive = defaults.Predictive
+	}
+	t.Predictive = predictive.resolve()
+	if t.ConnectionAggregation == "" {
+		t.ConnectionAggregation = defaultConnectionAggregation
+	}
+	if t.NodeAggregation == "" {
+		t.NodeAggregation = defaultNodeAggregation
+	}
+	if t.CooldownScope == "" {
+		t.CooldownScope = defaultCooldownScope
+	}
+	if t.ScalingPolicy == "" {
+		t.ScalingPolicy = defaultScalingPolicy
+	}
+	if ft.TargetTrackingTolerance == nil && defaults.TargetTrackingTolerance == nil {
+		t.TargetTrackingTolerance = defaultTargetTrackingTolerance
+	}
+	if t.Name == "" {
+		t.Name = defaultTargetName(t)
+	}
+
+	// Os erros são acumulados e o alvo é montado mesmo assim, para que a
+	// validação também aponte os problemas dos demais campos. Agendamentos e
+	// regras inválidos ficam de fora do alvo.
+	var errs []error
+	step, err := ParseStepPolicy(pick(ft.ScaleStep, defaults.ScaleStep))
+	if err != nil {
+		errs = append(errs, fmt.Errorf("scaleStep: %w", err))
+	}
+	t.ScaleStep = step
+
+	if t.Timezone != "" {
+		if _, err := time.LoadLocation(t.Timezone); err != nil {
+			errs = append(errs, fmt.Errorf("timezone '%s' inválido: %w", t.Timezone, err))
+		}
+	}
+	for _, fs := range pick(ft.Schedules, defaults.Schedules) {
+		s, err := fs.resolve(t.Location())
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		t.Schedules = append(t.Schedules, s)
+	}
+
+	rules := pick(ft.Rules, defaults.Rules)
+	if rules == nil {
+		t.Rules = defaultRules(t)
+		return t, errors.Join(errs...)
+	}
+	for _, fr := range rules {
+		r, err := fr.resolve(t.NodeAggregation)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		t.Rules = append(t.Rules, r)
+	}
+	return t, errors.Join(errs...)
+}
+
+// pick retorna o primeiro valor definido entre o alvo e o padrão
+func pick[T any](value, fallback *T) T {
+	if value != nil {
+		return *value
+	}
+	if fallback != nil {
+		return *fallback
+	}
+	var zero T
+	return zero
+}
+
+// defaultTargetName retorna o nome usado quando o alvo não define um
+func defaultTargetName(t Target) string {
+	return t.ClusterName + "/" + t.InstanceName
+}
@@ -0,0 +1,149 @@
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+// validFile é um arquivo mínimo válido; os casos de teste acrescentam campos
+// ao alvo
+const validFile = `
+version: 1
+timeoutSeconds: 30
+defaults:
+  gcpProject: project
+  region: us-central1
+  clusterName: cluster
+  checkInterval: 60
+  minReplicas: 1
+  maxReplicas: 5
+targets:
+  - name: reports
+    instanceName: read-pool
+`
+
+func TestParseFile(t *testing.T) {
+	tests := []struct {
+		name     string
+		target   string
+		wantErrs []string
+	}{
+		{name: "valid"},
+		{
+			name:     "target without instance name",
+			target:   "  - name: empty\n",
+			wantErrs: []string{"alvo 'empty': INSTANCE_NAME não pode ser vazio"},
+		},
+		{
+			name:     "invalid scale step",
+			target:   "    scaleStep: abc\n",
+			wantErrs: []string{"targets[0]: scaleStep: passo 'abc' inválido"},
+		},
+		{
+			name:   "invalid scale step and inverted limits",
+			target: "    scaleStep: abc\n    minReplicas: 4\n    maxReplicas: 2\n",
+			wantErrs: []string{
+				"targets[0]: scaleStep: passo 'abc' inválido",
+				"alvo 'reports': MIN_REPLICAS (4) não pode ser maior que MAX_REPLICAS (2)",
+			},
+		},
+		{
+			name:   "inverted limits and invalid interval",
+			target: "    minReplicas: 4\n    maxReplicas: 2\n    checkInterval: 0\n",
+			wantErrs: []string{
+				"alvo 'reports': MIN_REPLICAS (4) não pode ser maior que MAX_REPLICAS (2)",
+				"alvo 'reports': CHECK_INTERVAL deve ser maior que 0",
+			},
+		},
+		{
+			name:   "invalid timezone and schedule",
+			target: "    timezone: Mars/Olympus\n    schedules:\n      - name: night\n        start: \"0 22 * * *\"\n        duration: soon\n        replicas: 2\n",
+			wantErrs: []string{
+				"timezone 'Mars/Olympus' inválido",
+				"agendamento 'night': duration 'soon' inválida",
+			},
+		},
+		{
+			name:   "rule without threshold and negative cooldown",
+			target: "    scaleUpCooldown: -1\n    rules:\n      - name: cpu\n        metric: alloydb.googleapis.com/instance/cpu/average_utilization\n",
+			wantErrs: []string{
+				"regra 'cpu': threshold é obrigatório",
+				"alvo 'reports': SCALE_UP_COOLDOWN não pode ser negativo",
+			},
+		},
+		{
+			name:   "rule without threshold and invalid direction",
+			target: "    rules:\n      - name: cpu\n        metric: alloydb.googleapis.com/instance/cpu/average_utilization\n      - name: load\n        metric: custom.googleapis.com/load\n        threshold: 70\n        direction: sideways\n",
+			wantErrs: []string{
+				"regra 'cpu': threshold é obrigatório",
+				"alvo 'reports': regra 'load': direction 'sideways' inválida",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := parseFile("config.yaml", []byte(validFile+tt.target))
+			if len(tt.wantErrs) == 0 {
+				if err != nil {
+					t.Fatalf("parseFile() error = %v", err)
+				}
+				if len(c.Targets) != 1 || c.Targets[0].InstancePath() != "projects/project/locations/us-central1/clusters/cluster/instances/read-pool" {
+					t.Errorf("targets = %+v", c.Targets)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatalf("parseFile() error = nil, want %q", tt.wantErrs)
+			}
+			for _, want := range tt.wantErrs {
+				if !strings.Contains(err.Error(), want) {
+					t.Errorf("parseFile() error = %q, want it to contain %q", err, want)
+				}
+			}
+		})
+	}
+}
+
+func TestParseFileDefaults(t *testing.T) {
+	c, err := parseFile("config.yaml", []byte(validFile+"  - instanceName: other-pool\n    maxReplicas: 8\n"))
+	if err != nil {
+		t.Fatalf("parseFile() error = %v", err)
+	}
+	if len(c.Targets[0].Rules) == 0 {
+		t.Error("no default rules built from the thresholds")
+	}
+	if got := c.Targets[0].NodeAggregation; got != AggregationMax {
+		t.Errorf("NodeAggregation = %q, want %q", got, AggregationMax)
+	}
+	if c.OperationMaxWaitSeconds != defaultOperationMaxWaitSeconds || c.HTTPAddr != defaultHTTPAddr {
+		t.Errorf("operationMaxWaitSeconds/httpAddr = %d/%q, want defaults", c.OperationMaxWaitSeconds, c.HTTPAddr)
+	}
+	other := c.Targets[1]
+	if other.Name != "cluster/other-pool" {
+		t.Errorf("Name = %q, want cluster/other-pool", other.Name)
+	}
+	if other.MaxReplicas != 8 || other.MinReplicas != 1 || other.CheckInterval != 60 {
+		t.Errorf("min/max/interval = %d/%d/%d, want 1/8/60", other.MinReplicas, other.MaxReplicas, other.CheckInterval)
+	}
+}
+
+func TestParseFileRejects(t *testing.T) {
+	tests := []struct {
+		name    string
+		path    string
+		data    string
+		wantErr string
+	}{
+		{name: "unknown field", path: "config.yaml", data: "version: 1\nunknown: true\n", wantErr: "field unknown not found"},
+		{name: "unknown json field", path: "config.json", data: `{"version": 1, "unknown": true}`, wantErr: `unknown field "unknown"`},
+		{name: "unsupported version", path: "config.yaml", data: "version: 2\n", wantErr: "versão 2 não suportada"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := parseFile(tt.path, []byte(tt.data))
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("parseFile() error = %v, want it to contain %q", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,130 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Valores padrão da escala preditiva
+const (
+	defaultPredictiveHistoryWeeks     = 4
+	defaultPredictiveLookaheadMinutes = 30
+	defaultPredictiveRefreshMinutes   = 60
+	// defaultPredictiveHistoryTimeoutSeconds é maior que o prazo de um ciclo
+	// porque as consultas cobrem semanas de histórico
+	defaultPredictiveHistoryTimeoutSeconds = 120
+	// defaultNodeCountMetric informa o número de nós de cada instância, usado
+	// para converter o histórico das regras em carga total do read pool
+	defaultNodeCountMetric = "alloydb.googleapis.com/instance/postgres/instances"
+
+	// maxPredictiveHistoryWeeks é a retenção do Cloud Monitoring para métricas
+	// do AlloyDB
+	maxPredictiveHistoryWeeks = 6
+)
+
+// Predictive configura a escala preditiva de um alvo: um perfil sazonal por dia
+// da semana e hora, montado a partir do histórico das regras, define um mínimo
+// de réplicas antes dos picos previstos
+type Predictive struct {
+	Enabled bool
+	// Rules são os nomes das regras previstas; vazio usa cpu e memory
+	Rules []string
+	// HistoryWeeks é quantas semanas de histórico compõem o perfil
+	HistoryWeeks int
+	// LookaheadMinutes é a antecedência com que um pico previsto é atendido
+	LookaheadMinutes int
+	// RefreshMinutes é o intervalo entre reconstruções do perfil
+	RefreshMinutes int
+	// HistoryTimeoutSeconds é o prazo das consultas ao histórico em cada reconstrução
+	HistoryTimeoutSeconds int
+	// NodeCountMetric é a métrica com o número de nós ao longo do tempo
+	NodeCountMetric string
+}
+
+// defaultPredictive retorna a configuração preditiva com os valores padrão
+func defaultPredictive() Predictive {
+	return Predictive{
+		HistoryWeeks:     defaultPredictiveHistoryWeeks,
+		LookaheadMinutes: defaultPredictiveLookaheadMinutes,
+		RefreshMinutes:   defaultPredictiveRefreshMinutes,
+		NodeCountMetric:  defaultNodeCountMetric,
+
+		HistoryTimeoutSeconds: defaultPredictiveHistoryTimeoutSeconds,
+	}
+}
+
+// PredictiveRules devolve as regras do alvo usadas na previsão
+func (t Target) PredictiveRules() []Rule {
+	names := t.Predictive.Rules
+	if len(names) == 0 {
+		names = []string{"cpu", "memory"}
+	}
+	var rules []Rule
+	for _, name := range names {
+		for _, r := range t.Rules {
+			if r.Name == name {
+				rules = append(rules, r)
+			}
+		}
+	}
+	return rules
+}
+
+// parseRuleList interpreta uma lista de nomes separados por vírgula
+func parseRuleList(value string) []string {
+	var names []string
+	for _, name := range strings.Split(value, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// validatePredictive verifica a configuração preditiva de um alvo
+func validatePredictive(t Target) []error {
+	p := t.Predictive
+	if !p.Enabled {
+		return nil
+	}
+
+	var errs []error
+	addf := func(format string, args ...any) {
+		errs = append(errs, fmt.Errorf(format, args...))
+	}
+	if p.HistoryWeeks < 1 || p.HistoryWeeks > maxPredictiveHistoryWeeks {
+		addf("PREDICTIVE_HISTORY_WEEKS deve estar entre 1 e %d, valor atual: %d", maxPredictiveHistoryWeeks, p.HistoryWeeks)
+	}
+	if p.LookaheadMinutes < 0 {
+		addf("PREDICTIVE_LOOKAHEAD_MINUTES não pode ser negativo, valor atual: %d", p.LookaheadMinutes)
+	}
+	if p.RefreshMinutes <= 0 {
+		addf("PREDICTIVE_REFRESH_MINUTES deve ser maior que 0, valor atual: %d", p.RefreshMinutes)
+	}
+	if p.HistoryTimeoutSeconds <= 0 {
+		addf("PREDICTIVE_HISTORY_TIMEOUT_SECONDS deve ser maior que 0, valor atual: %d", p.HistoryTimeoutSeconds)
+	}
+	if p.NodeCountMetric == "" {
+		addf("a métrica de número de nós da escala preditiva não pode ser vazia")
+	}
+
+	for _, name := range p.Rules {
+		found := false
+		for _, r := range t.Rules {
+			if r.Name != name {
+				continue
+			}
+			found = true
+			if !r.VotesUp() {
+				addf("regra preditiva '%s' precisa votar para aumentar (direction up ou both e weight maior que 0)", name)
+			}
+		}
+		if !found {
+			addf("regra preditiva '%s' não existe", name)
+		}
+	}
+	if len(t.PredictiveRules()) == 0 {
+		addf("nenhuma regra para a escala preditiva; defina PREDICTIVE_RULES")
+	}
+	return errs
+}
@@ -0,0 +1,196 @@
+package config
+
+import (
+	"fmt"
+
+	"cloud.google.com/go/monitoring/apiv3/v2/monitoringpb"
+)
+
+// Direções de uma regra
+const (
+	// DirectionUp vota para aumentar acima do limite, sem impedir reduções
+	DirectionUp = "up"
+	// DirectionDown só participa da redução: precisa estar abaixo do limite
+	DirectionDown = "down"
+	// DirectionBoth vota para aumentar acima do limite e precisa estar abaixo
+	// dele para reduzir
+	DirectionBoth = "both"
+)
+
+// Conversões aplicadas ao valor bruto de uma métrica antes da comparação
+const (
+	ConversionNone = "none"
+	// ConversionPercent converte uma razão 0–1 em percentual
+	ConversionPercent = "percent"
+	// ConversionBytesToGiB converte bytes em GiB
+	ConversionBytesToGiB = "bytes_to_gib"
+	// ConversionUsedMemoryPercent converte memória livre em bytes no
+	// percentual usado da memória total do nó
+	ConversionUsedMemoryPercent = "used_memory_percent"
+	// ConversionConnectionPercent converte conexões por nó em percentual de
+	// max_connections
+	ConversionConnectionPercent = "connection_percent"
+)
+
+// Agregações dos valores de cada nó do read pool em um valor da regra
+const (
+	// AggregationMax usa o nó mais carregado
+	AggregationMax = "max"
+	// AggregationMean usa a média dos nós
+	AggregationMean = "mean"
+	// AggregationP95 usa o percentil 95 dos nós
+	AggregationP95 = "p95"
+)
+
+// validAggregation informa se value é uma agregação entre nós conhecida
+func validAggregation(value string) bool {
+	return value == AggregationMax || value == AggregationMean || value == AggregationP95
+}
+
+// Rule é uma regra declarativa de escala sobre uma métrica do Cloud Monitoring
+type Rule struct {
+	Name   string
+	Metric string
+	// Aligner e Reducer são nomes de Aggregation do Cloud Monitoring
+	// (ex.: ALIGN_MEAN, REDUCE_MAX), aplicados no servidor. Sem Aligner cada
+	// série é alinhada pela média; sem Reducer as séries não são combinadas.
+	Aligner    string
+	Reducer    string
+	Conversion string
+	// NodeAggregation combina as séries restantes, já convertidas, em um
+	// único valor: AggregationMax, AggregationMean ou AggregationP95
+	NodeAggregation string
+	// Scale multiplica o valor depois da conversão
+	Scale float64
+	// Threshold é o limite para aumentar e ScaleDownThreshold o limite para
+	// reduzir; entre os dois a regra não vota
+	Threshold          float64
+	ScaleDownThreshold float64
+	Direction          string
+	// Weight é o peso do voto para aumentar; a soma dos pesos das regras
+	// acima do limite precisa chegar a 1. Peso 0 apenas registra o valor.
+	Weight float64
+}
+
+// VotesUp informa se a regra pode votar para aumentar
+func (r Rule) VotesUp() bool {
+	return r.Weight > 0 && r.Direction != DirectionDown
+}
+
+// GuardsDown informa se a regra precisa estar abaixo do limite para reduzir
+func (r Rule) GuardsDown() bool {
+	return r.Weight > 0 && r.Direction != DirectionUp
+}
+
+// defaultRules reproduz as verificações de CPU, memória e conexões a partir
+// dos limites do alvo, usadas quando nenhuma regra é configurada
+func defaultRules(t Target) []Rule {
+	rules := []Rule{
+		{
+			Name:            "cpu",
+			Metric:          defaultCPUMetric,
+			Conversion:      ConversionPercent,
+			NodeAggregation: t.NodeAggregation,
+			Scale:           1,
+			Threshold:       t.CPUThreshold,
+			Direction:       DirectionBoth,
+			Weight:          1,
+
+			ScaleDownThreshold: scaleDownThreshold(t.CPUScaleDownThreshold, t.CPUThreshold),
+		},
+		{
+			Name:            "memory",
+			Metric:          defaultMemoryMetric,
+			Conversion:      ConversionUsedMemoryPercent,
+			NodeAggregation: t.NodeAggregation,
+			Scale:           1,
+			Threshold:       t.MemoryThreshold,
+			Direction:       DirectionBoth,
+			Weight:          1,
+
+			ScaleDownThreshold: scaleDownThreshold(t.MemoryScaleDownThreshold, t.MemoryThreshold),
+		},
+	}
+	if t.ConnectionThreshold > 0 {
+		rules = append(rules, Rule{
+			Name:            "connections",
+			Metric:          defaultConnectionMetric,
+			Conversion:      ConversionConnectionPercent,
+			NodeAggregation: t.ConnectionAggregation,
+			Scale:           1,
+			Threshold:       t.ConnectionThreshold,
+			Direction:       DirectionBoth,
+			Weight:          1,
+
+			ScaleDownThreshold: scaleDownThreshold(t.ConnectionScaleDownThreshold, t.ConnectionThreshold),
+		})
+	}
+	return rules
+}
+
+// scaleDownThreshold usa o limite de aumento quando o de redução não foi definido
+func scaleDownThreshold(down, up float64) float64 {
+	if down == 0 {
+		return up
+	}
+	return down
+}
+
+// validateRules verifica as regras de um alvo; os erros usam o índice da regra
+func validateRules(rules []Rule) []error {
+	var errs []error
+	addf := func(format string, args ...any) {
+		errs = append(errs, fmt.Errorf(format, args...))
+	}
+
+	if len(rules) == 0 {
+		addf("nenhuma regra de escala configurada")
+	}
+	seen := make(map[string]bool, len(rules))
+	for i, r := range rules {
+		label := fmt.Sprintf("rules[%d]", i)
+		if r.Name == "" {
+			addf("%s: name não pode ser vazio", label)
+		} else {
+			label = fmt.Sprintf("regra '%s'", r.Name)
+			if seen[r.Name] {
+				addf("%s está definida mais de uma vez", label)
+			}
+			seen[r.Name] = true
+		}
+
+		if r.Metric == "" {
+			addf("%s: metric não pode ser vazio", label)
+		}
+		if _, ok := monitoringpb.Aggregation_Aligner_value[r.Aligner]; r.Aligner != "" && !ok {
+			addf("%s: aligner '%s' inválido", label, r.Aligner)
+		}
+		if _, ok := monitoringpb.Aggregation_Reducer_value[r.Reducer]; r.Reducer != "" && !ok {
+			addf("%s: reducer '%s' inválido", label, r.Reducer)
+		}
+		if !validAggregation(r.NodeAggregation) {
+			addf("%s: nodeAggregation '%s' inválida; use %s, %s ou %s", label, r.NodeAggregation, AggregationMax, AggregationMean, AggregationP95)
+		}
+		switch r.Conversion {
+		case ConversionNone, ConversionPercent, ConversionBytesToGiB, ConversionUsedMemoryPercent, ConversionConnectionPercent:
+		default:
+			addf("%s: conversion '%s' inválida; use %s, %s, %s, %s ou %s", label, r.Conversion,
+				ConversionNone, ConversionPercent, ConversionBytesToGiB, ConversionUsedMemoryPercent, ConversionConnectionPercent)
+		}
+		switch r.Direction {
+		case DirectionUp, DirectionDown, DirectionBoth:
+		default:
+			addf("%s: direction '%s' inválida; use up, down ou both", label, r.Direction)
+		}
+		if r.ScaleDownThreshold > r.Threshold {
+			addf("%s: o limite para reduzir (%g) não pode ser maior que o limite para aumentar (%g)", label, r.ScaleDownThreshold, r.Threshold)
+		}
+		if r.Scale == 0 {
+			addf("%s: scale não pode ser 0", label)
+		}
+		if r.Weight < 0 {
+			addf("%s: weight não pode ser negativo, valor atual: %g", label, r.Weight)
+		}
+	}
+	return errs
+}
@@ -0,0 +1,133 @@
+package config
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/robfig/cron/v3"
+)
+
+// Schedule sobrescreve os limites de réplicas de um alvo em uma janela
+// recorrente, que começa a cada disparo de Start e dura Duration
+type Schedule struct {
+	Name string
+	// Start é uma expressão cron de 5 campos, avaliada no fuso do alvo
+	Start    string
+	Duration time.Duration
+	// MinReplicas e MaxReplicas substituem os limites do alvo; 0 mantém o
+	// limite do alvo
+	MinReplicas int
+	MaxReplicas int
+	// Replicas fixa o número de nós durante a janela; exclusivo com
+	// MinReplicas e MaxReplicas
+	Replicas int
+
+	spec cron.Schedule
+}
+
+// parseSchedule interpreta a expressão cron de s no fuso loc
+func parseSchedule(s Schedule, loc *time.Location) (Schedule, error) {
+	spec, err := cron.ParseStandard(fmt.Sprintf("CRON_TZ=%s %s", loc, s.Start))
+	if err != nil {
+		return s, fmt.Errorf("agendamento '%s': start '%s' inválido: %w", s.Name, s.Start, err)
+	}
+	s.spec = spec
+	return s, nil
+}
+
+// Active informa se a janela do agendamento contém now
+func (s Schedule) Active(now time.Time) bool {
+	if s.spec == nil {
+		return false
+	}
+	// A janela está ativa se houve um disparo em (now-Duration, now]. Next
+	// devolve o tempo zero quando a expressão nunca dispara.
+	next := s.spec.Next(now.Add(-s.Duration))
+	return !next.IsZero() && !next.After(now)
+}
+
+// Limits devolve os limites de réplicas do alvo com o agendamento aplicado
+func (s Schedule) Limits(minReplicas, maxReplicas int) (int, int) {
+	if s.Replicas > 0 {
+		return s.Replicas, s.Replicas
+	}
+	if s.MinReplicas > 0 {
+		minReplicas = s.MinReplicas
+	}
+	if s.MaxReplicas > 0 {
+		maxReplicas = s.MaxReplicas
+	}
+	return minReplicas, maxReplicas
+}
+
+// Location devolve o fuso de Timezone, ou o fuso local quando não definido
+func (t Target) Location() *time.Location {
+	if t.Timezone == "" {
+		return time.Local
+	}
+	loc, err := time.LoadLocation(t.Timezone)
+	if err != nil {
+		return time.Local
+	}
+	return loc
+}
+
+// WithSchedule devolve o alvo com os limites do primeiro agendamento ativo em
+// now. O segundo retorno é o agendamento aplicado, nil quando nenhum está ativo.
+func (t Target) WithSchedule(now time.Time) (Target, *Schedule) {
+	for i := range t.Schedules {
+		if t.Schedules[i].Active(now) {
+			s := t.Schedules[i]
+			t.MinReplicas, t.MaxReplicas = s.Limits(t.MinReplicas, t.MaxReplicas)
+			return t, &s
+		}
+	}
+	return t, nil
+}
+
+// validateSchedules verifica os agendamentos de um alvo contra os limites dele
+func validateSchedules(t Target) []error {
+	var errs []error
+	addf := func(format string, args ...any) {
+		errs = append(errs, fmt.Errorf(format, args...))
+	}
+
+	seen := make(map[string]bool, len(t.Schedules))
+	for i, s := range t.Schedules {
+		label := fmt.Sprintf("schedules[%d]", i)
+		if s.Name == "" {
+			addf("%s: name não pode ser vazio", label)
+		} else {
+			label = fmt.Sprintf("agendamento '%s'", s.Name)
+			if seen[s.Name] {
+				addf("%s está definido mais de uma vez", label)
+			}
+			seen[s.Name] = true
+		}
+
+		if s.Duration <= 0 {
+			addf("%s: duration deve ser maior que 0", label)
+		}
+		if s.Replicas < 0 || s.MinReplicas < 0 || s.MaxReplicas < 0 {
+			addf("%s: replicas, minReplicas e maxReplicas não podem ser negativos", label)
+			continue
+		}
+		if s.Replicas > 0 && (s.MinReplicas > 0 || s.MaxReplicas > 0) {
+			addf("%s: use replicas ou minReplicas/maxReplicas, não ambos", label)
+			continue
+		}
+		if s.Replicas == 0 && s.MinReplicas == 0 && s.MaxReplicas == 0 {
+			addf("%s: defina replicas, minReplicas ou maxReplicas", label)
+			continue
+		}
+
+		minReplicas, maxReplicas := s.Limits(t.MinReplicas, t.MaxReplicas)
+		if minReplicas > maxReplicas {
+			addf("%s: mínimo de réplicas (%d) maior que o máximo (%d) durante a janela", label, minReplicas, maxReplicas)
+		}
+		if maxReplicas > maxReplicasLimit {
+			addf("%s: réplicas não podem exceder %d, valor atual: %d", label, maxReplicasLimit, maxReplicas)
+		}
+	}
+	return errs
+}
@@ -0,0 +1,108 @@
+package config
+
+import (
+	"testing"
+	"time"
+)
+
+func TestScheduleActive(t *testing.T) {
+	saoPaulo, err := time.LoadLocation("America/Sao_Paulo")
+	if err != nil {
+		t.Skipf("time zone database unavailable: %v", err)
+	}
+	// Janela das 22h às 0h no fuso do alvo, 01h às 03h em UTC
+	night := Schedule{Name: "night", Start: "0 22 * * *", Duration: 2 * time.Hour}
+
+	tests := []struct {
+		name string
+		loc  *time.Location
+		now  time.Time
+		want bool
+	}{
+		{name: "before the window", loc: saoPaulo, now: time.Date(2024, 6, 1, 0, 59, 0, 0, time.UTC), want: false},
+		{name: "window start", loc: saoPaulo, now: time.Date(2024, 6, 1, 1, 0, 0, 0, time.UTC), want: true},
+		{name: "inside the window", loc: saoPaulo, now: time.Date(2024, 6, 1, 2, 30, 0, 0, time.UTC), want: true},
+		{name: "window end", loc: saoPaulo, now: time.Date(2024, 6, 1, 3, 0, 0, 0, time.UTC), want: false},
+		{name: "same instant in UTC is before 22h", loc: time.UTC, now: time.Date(2024, 6, 1, 2, 30, 0, 0, time.UTC), want: false},
+		{name: "UTC window", loc: time.UTC, now: time.Date(2024, 6, 1, 23, 30, 0, 0, time.UTC), want: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := parseSchedule(night, tt.loc)
+			if err != nil {
+				t.Fatalf("parseSchedule() error = %v", err)
+			}
+			if got := s.Active(tt.now); got != tt.want {
+				t.Errorf("Active(%s) = %v, want %v", tt.now.In(tt.loc), got, tt.want)
+			}
+		})
+	}
+}
+
+func TestScheduleActiveUnparsed(t *testing.T) {
+	s := Schedule{Name: "night", Start: "0 22 * * *", Duration: time.Hour}
+	if s.Active(time.Date(2024, 6, 1, 22, 30, 0, 0, time.Local)) {
+		t.Error("schedule without a parsed cron expression is active")
+	}
+}
+
+func TestParseScheduleInvalid(t *testing.T) {
+	if _, err := parseSchedule(Schedule{Name: "bad", Start: "0 25 * * *"}, time.UTC); err == nil {
+		t.Error("parseSchedule() accepted hour 25")
+	}
+}
+
+func TestTargetWithSchedule(t *testing.T) {
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	active := func(s Schedule) Schedule {
+		t.Helper()
+		s.Start = "0 11 * * *"
+		s.Duration = 2 * time.Hour
+		parsed, err := parseSchedule(s, time.UTC)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return parsed
+	}
+	inactive, err := parseSchedule(Schedule{Name: "night", Start: "0 22 * * *", Duration: time.Hour, Replicas: 1}, time.UTC)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name         string
+		schedules    []Schedule
+		wantSchedule string
+		wantMin      int
+		wantMax      int
+	}{
+		{name: "no schedule", wantMin: 2, wantMax: 6},
+		{name: "inactive schedule", schedules: []Schedule{inactive}, wantMin: 2, wantMax: 6},
+		{name: "fixed replicas", schedules: []Schedule{active(Schedule{Name: "peak", Replicas: 4})}, wantSchedule: "peak", wantMin: 4, wantMax: 4},
+		{name: "only min", schedules: []Schedule{active(Schedule{Name: "peak", MinReplicas: 3})}, wantSchedule: "peak", wantMin: 3, wantMax: 6},
+		{name: "only max", schedules: []Schedule{active(Schedule{Name: "quiet", MaxReplicas: 3})}, wantSchedule: "quiet", wantMin: 2, wantMax: 3},
+		{
+			name:         "first active schedule wins",
+			schedules:    []Schedule{inactive, active(Schedule{Name: "first", Replicas: 5}), active(Schedule{Name: "second", Replicas: 3})},
+			wantSchedule: "first",
+			wantMin:      5,
+			wantMax:      5,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			target := Target{MinReplicas: 2, MaxReplicas: 6, Schedules: tt.schedules}
+			got, schedule := target.WithSchedule(now)
+			name := ""
+			if schedule != nil {
+				name = schedule.Name
+			}
+			if name != tt.wantSchedule {
+				t.Errorf("schedule = %q, want %q", name, tt.wantSchedule)
+			}
+			if got.MinReplicas != tt.wantMin || got.MaxReplicas != tt.wantMax {
+				t.Errorf("limits = %d-%d, want %d-%d", got.MinReplicas, got.MaxReplicas, tt.wantMin, tt.wantMax)
+			}
+		})
+	}
+}
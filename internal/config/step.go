@@ -0,0 +1,115 @@
+package config
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Modos de StepPolicy
+const (
+	StepFixed   = "fixed"
+	StepPercent = "percent"
+	StepBreach  = "breach"
+)
+
+// StepPolicy define quantos nós adicionar ou remover em uma decisão de escala.
+// O valor zero equivale a um passo fixo de 1 nó.
+type StepPolicy struct {
+	Mode string
+	// Value é o número de nós (fixed) ou o percentual dos nós atuais (percent)
+	Value float64
+	// Tiers são os degraus do modo breach, em ordem crescente de Over
+	Tiers []BreachTier
+}
+
+// BreachTier aplica Step nós quando a métrica ultrapassa o limite em pelo
+// menos Over por cento do próprio limite
+type BreachTier struct {
+	Over float64
+	Step int
+}
+
+// ParseStepPolicy interpreta ESCALAR_THRESHOLD / scaleStep:
+//
+//	"2"          passo fixo de 2 nós
+//	"50%"        50% dos nós atuais, arredondado para cima
+//	"10:1,40:3"  1 nó a partir de 10% além do limite, 3 nós a partir de 40%
+func ParseStepPolicy(value string) (StepPolicy, error) {
+	value = strings.TrimSpace(value)
+	switch {
+	case value == "":
+		return StepPolicy{}, nil
+
+	case strings.Contains(value, ":"):
+		var tiers []BreachTier
+		for _, part := range strings.Split(value, ",") {
+			overStr, stepStr, _ := strings.Cut(strings.TrimSpace(part), ":")
+			over, err := strconv.ParseFloat(strings.TrimSuffix(strings.TrimSpace(overStr), "%"), 64)
+			if err != nil || over < 0 {
+				return StepPolicy{}, fmt.Errorf("degrau '%s' inválido: o percentual além do limite deve ser um número não negativo", part)
+			}
+			step, err := strconv.Atoi(strings.TrimSpace(stepStr))
+			if err != nil || step < 1 {
+				return StepPolicy{}, fmt.Errorf("degrau '%s' inválido: o passo deve ser um inteiro maior que 0", part)
+			}
+			tiers = append(tiers, BreachTier{Over: over, Step: step})
+		}
+		sort.Slice(tiers, func(i, j int) bool { return tiers[i].Over < tiers[j].Over })
+		return StepPolicy{Mode: StepBreach, Tiers: tiers}, nil
+
+	case strings.HasSuffix(value, "%"):
+		percent, err := strconv.ParseFloat(strings.TrimSuffix(value, "%"), 64)
+		if err != nil || percent <= 0 {
+			return StepPolicy{}, fmt.Errorf("percentual '%s' inválido: deve ser maior que 0", value)
+		}
+		return StepPolicy{Mode: StepPercent, Value: percent}, nil
+
+	default:
+		step, err := strconv.Atoi(value)
+		if err != nil || step < 1 {
+			return StepPolicy{}, fmt.Errorf("passo '%s' inválido: use N, N%% ou limite:passo,limite:passo", value)
+		}
+		return StepPolicy{Mode: StepFixed, Value: float64(step)}, nil
+	}
+}
+
+// Step calcula o passo para currentNodes nós, com a métrica breach por cento
+// além do limite. O resultado é sempre pelo menos 1; o ajuste a
+// MinReplicas/MaxReplicas fica a cargo de quem aplica o passo.
+func (p StepPolicy) Step(currentNodes int, breach float64) int {
+	step := 1
+	switch p.Mode {
+	case StepFixed:
+		step = int(p.Value)
+	case StepPercent:
+		step = int(math.Ceil(float64(currentNodes) * p.Value / 100))
+	case StepBreach:
+		for _, tier := range p.Tiers {
+			if breach >= tier.Over {
+				step = tier.Step
+			}
+		}
+	}
+	return max(step, 1)
+}
+
+// String devolve a política no mesmo formato aceito por ParseStepPolicy
+func (p StepPolicy) String() string {
+	switch p.Mode {
+	case StepFixed:
+		return strconv.Itoa(int(p.Value))
+	case StepPercent:
+		return strconv.FormatFloat(p.Value, 'f', -1, 64) + "%"
+	case StepBreach:
+		parts := make([]string, len(p.Tiers))
+		for i, tier := range p.Tiers {
+			parts[i] = fmt.Sprintf("%s:%d", strconv.FormatFloat(tier.Over, 'f', -1, 64), tier.Step)
+		}
+		return strings.Join(parts, ",")
+	default:
+		return "1"
+	}
+}
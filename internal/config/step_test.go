@@ -0,0 +1,89 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseStepPolicy(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    StepPolicy
+		wantErr bool
+	}{
+		{value: "", want: StepPolicy{}},
+		{value: " 2 ", want: StepPolicy{Mode: StepFixed, Value: 2}},
+		{value: "50%", want: StepPolicy{Mode: StepPercent, Value: 50}},
+		{value: "12.5%", want: StepPolicy{Mode: StepPercent, Value: 12.5}},
+		{
+			value: "40:3, 10%:1",
+			want:  StepPolicy{Mode: StepBreach, Tiers: []BreachTier{{Over: 10, Step: 1}, {Over: 40, Step: 3}}},
+		},
+		{value: "0", wantErr: true},
+		{value: "-1", wantErr: true},
+		{value: "1.5", wantErr: true},
+		{value: "abc", wantErr: true},
+		{value: "0%", wantErr: true},
+		{value: "x%", wantErr: true},
+		{value: "10:0", wantErr: true},
+		{value: "-5:1", wantErr: true},
+		{value: "10:1,abc:2", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got, err := ParseStepPolicy(tt.value)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("ParseStepPolicy(%q) = %+v, want error", tt.value, got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseStepPolicy(%q) error = %v", tt.value, err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseStepPolicy(%q) = %+v, want %+v", tt.value, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStepPolicyStep(t *testing.T) {
+	tests := []struct {
+		policy string
+		nodes  int
+		breach float64
+		want   int
+	}{
+		{policy: "", nodes: 4, want: 1},
+		{policy: "3", nodes: 4, want: 3},
+		{policy: "50%", nodes: 4, want: 2},
+		{policy: "50%", nodes: 3, want: 2},
+		{policy: "10%", nodes: 1, want: 1},
+		{policy: "10:2,40:4", nodes: 2, breach: 5, want: 1},
+		{policy: "10:2,40:4", nodes: 2, breach: 10, want: 2},
+		{policy: "10:2,40:4", nodes: 2, breach: 39.9, want: 2},
+		{policy: "10:2,40:4", nodes: 2, breach: 80, want: 4},
+	}
+	for _, tt := range tests {
+		policy, err := ParseStepPolicy(tt.policy)
+		if err != nil {
+			t.Fatalf("ParseStepPolicy(%q) error = %v", tt.policy, err)
+		}
+		if got := policy.Step(tt.nodes, tt.breach); got != tt.want {
+			t.Errorf("%q.Step(%d, %g) = %d, want %d", tt.policy, tt.nodes, tt.breach, got, tt.want)
+		}
+	}
+}
+
+func TestStepPolicyString(t *testing.T) {
+	for _, value := range []string{"1", "2", "50%", "12.5%", "10:1,40:3"} {
+		policy, err := ParseStepPolicy(value)
+		if err != nil {
+			t.Fatalf("ParseStepPolicy(%q) error = %v", value, err)
+		}
+		if got := policy.String(); got != value {
+			t.Errorf("ParseStepPolicy(%q).String() = %q", value, got)
+		}
+	}
+}
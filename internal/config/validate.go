@@ -0,0 +1,162 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+)
+
+// maxReplicasLimit é o limite de nós de um read pool do AlloyDB
+const maxReplicasLimit = 20
+
+// validate verifica a configuração completa e reporta todos os problemas de uma vez
+func validate(c Config) error {
+	var errs []error
+	addf := func(format string, args ...any) {
+		errs = append(errs, fmt.Errorf(format, args...))
+	}
+
+	if c.TimeoutSeconds <= 0 {
+		addf("TIMEOUT_SECONDS deve ser maior que 0, valor atual: %d", c.TimeoutSeconds)
+	}
+	if c.LivenessMultiplier <= 0 {
+		addf("HEALTH_LIVENESS_MULTIPLIER deve ser maior que 0, valor atual: %g", c.LivenessMultiplier)
+	}
+	if c.ReadinessMultiplier <= 0 {
+		addf("HEALTH_READINESS_MULTIPLIER deve ser maior que 0, valor atual: %g", c.ReadinessMultiplier)
+	}
+	if c.ShutdownGraceSeconds < 0 {
+		addf("SHUTDOWN_GRACE_SECONDS não pode ser negativo, valor atual: %d", c.ShutdownGraceSeconds)
+	}
+	if c.OperationMaxWaitSeconds <= 0 {
+		addf("OPERATION_MAX_WAIT_SECONDS deve ser maior que 0, valor atual: %d", c.OperationMaxWaitSeconds)
+	}
+	errs = append(errs, validateLeaderElection(c.LeaderElection))
+	if len(c.Targets) == 0 {
+		addf("nenhum alvo configurado")
+	}
+
+	seen := make(map[string]bool, len(c.Targets))
+	for _, t := range c.Targets {
+		if seen[t.Name] {
+			addf("o alvo '%s' está definido mais de uma vez", t.Name)
+		}
+		seen[t.Name] = true
+
+		targetf := func(format string, args ...any) {
+			addf("alvo '%s': "+format, append([]any{t.Name}, args...)...)
+		}
+
+		for _, field := range []struct{ key, value string }{
+			{"GCP_PROJECT", t.GCPProject},
+			{"REGION", t.Region},
+			{"CLUSTER_NAME", t.ClusterName},
+			{"INSTANCE_NAME", t.InstanceName},
+		} {
+			if field.value == "" {
+				targetf("%s não pode ser vazio", field.key)
+			}
+		}
+
+		if t.CheckInterval <= 0 {
+			targetf("CHECK_INTERVAL deve ser maior que 0, valor atual: %d", t.CheckInterval)
+		}
+		if t.Evaluation < 0 {
+			targetf("EVALUATION não pode ser negativo, valor atual: %d", t.Evaluation)
+		}
+		if t.MinReplicas < 1 {
+			targetf("MIN_REPLICAS deve ser pelo menos 1, valor atual: %d", t.MinReplicas)
+		}
+		if t.MaxReplicas > maxReplicasLimit {
+			targetf("MAX_REPLICAS não pode exceder %d, valor atual: %d", maxReplicasLimit, t.MaxReplicas)
+		}
+		if t.MinReplicas > t.MaxReplicas {
+			targetf("MIN_REPLICAS (%d) não pode ser maior que MAX_REPLICAS (%d)", t.MinReplicas, t.MaxReplicas)
+		}
+		for _, field := range []struct {
+			key   string
+			value float64
+		}{
+			{"CPU_SCALE_DOWN_THRESHOLD", t.CPUScaleDownThreshold},
+			{"MEMORY_SCALE_DOWN_THRESHOLD", t.MemoryScaleDownThreshold},
+			{"CONNECTION_SCALE_DOWN_THRESHOLD", t.ConnectionScaleDownThreshold},
+			{"MEMORY_TOTAL_GB", t.MemoryTotalGB},
+		} {
+			if field.value < 0 {
+				targetf("%s não pode ser negativo, valor atual: %g", field.key, field.value)
+			}
+		}
+		if t.ConnectionThreshold < 0 {
+			targetf("CONNECTION_THRESHOLD não pode ser negativo, valor atual: %g", t.ConnectionThreshold)
+		}
+		if !validAggregation(t.ConnectionAggregation) {
+			targetf("CONNECTION_AGGREGATION deve ser %s, %s ou %s, valor atual: '%s'", AggregationMax, AggregationMean, AggregationP95, t.ConnectionAggregation)
+		}
+		if !validAggregation(t.NodeAggregation) {
+			targetf("NODE_AGGREGATION deve ser %s, %s ou %s, valor atual: '%s'", AggregationMax, AggregationMean, AggregationP95, t.NodeAggregation)
+		}
+		if t.MaxConnections < 0 {
+			targetf("MAX_CONNECTIONS não pode ser negativo, valor atual: %d", t.MaxConnections)
+		}
+		if t.ScaleUpCooldown < 0 {
+			targetf("SCALE_UP_COOLDOWN não pode ser negativo, valor atual: %d", t.ScaleUpCooldown)
+		}
+		if t.ScaleDownCooldown < 0 {
+			targetf("SCALE_DOWN_COOLDOWN não pode ser negativo, valor atual: %d", t.ScaleDownCooldown)
+		}
+		if t.CooldownScope != CooldownOpposite && t.CooldownScope != CooldownAll {
+			targetf("COOLDOWN_SCOPE deve ser %s ou %s, valor atual: '%s'", CooldownOpposite, CooldownAll, t.CooldownScope)
+		}
+		if t.ScalingPolicy != PolicyStep && t.ScalingPolicy != PolicyTargetTracking {
+			targetf("SCALING_POLICY deve ser %s ou %s, valor atual: '%s'", PolicyStep, PolicyTargetTracking, t.ScalingPolicy)
+		}
+		if t.TargetTrackingTolerance < 0 || t.TargetTrackingTolerance >= 100 {
+			targetf("TARGET_TRACKING_TOLERANCE deve estar entre 0 e 100, valor atual: %g", t.TargetTrackingTolerance)
+		}
+		for _, err := range validateSchedules(t) {
+			targetf("%v", err)
+		}
+		for _, err := range validatePredictive(t) {
+			targetf("%v", err)
+		}
+		for _, err := range validateRules(t.Rules) {
+			targetf("%v", err)
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// validateLeaderElection verifica os campos exigidos pelo backend escolhido
+func validateLeaderElection(le LeaderElection) error {
+	var errs []error
+	addf := func(format string, args ...any) {
+		errs = append(errs, fmt.Errorf(format, args...))
+	}
+
+	switch le.Backend {
+	case "":
+		return nil
+	case "file":
+		if le.LockFile == "" {
+			addf("LEADER_ELECTION_LOCK_FILE é obrigatório com LEADER_ELECTION=file")
+		}
+	case "kubernetes":
+	case "gcs":
+		if le.Bucket == "" {
+			addf("LEADER_ELECTION_BUCKET é obrigatório com LEADER_ELECTION=gcs")
+		}
+	default:
+		addf("LEADER_ELECTION deve ser file, kubernetes ou gcs, valor atual: '%s'", le.Backend)
+	}
+
+	if le.Backend != "file" && le.LeaseName == "" {
+		addf("LEADER_ELECTION_LEASE_NAME não pode ser vazio")
+	}
+	if le.RenewSeconds <= 0 {
+		addf("LEADER_ELECTION_RENEW_SECONDS deve ser maior que 0, valor atual: %d", le.RenewSeconds)
+	}
+	if le.LeaseSeconds <= le.RenewSeconds {
+		addf("LEADER_ELECTION_LEASE_SECONDS (%d) deve ser maior que LEADER_ELECTION_RENEW_SECONDS (%d)", le.LeaseSeconds, le.RenewSeconds)
+	}
+	return errors.Join(errs...)
+}
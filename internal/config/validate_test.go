@@ -0,0 +1,152 @@
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+// validConfig devolve uma configuração com um alvo válido
+func validConfig() Config {
+	target := Target{
+		Name:                    "reports",
+		GCPProject:              "project",
+		Region:                  "us-central1",
+		ClusterName:             "cluster",
+		InstanceName:            "read-pool",
+		CPUThreshold:            70,
+		CheckInterval:           60,
+		MinReplicas:             1,
+		MaxReplicas:             5,
+		NodeAggregation:         AggregationMax,
+		ConnectionAggregation:   AggregationMax,
+		CooldownScope:           CooldownOpposite,
+		ScalingPolicy:           PolicyStep,
+		TargetTrackingTolerance: 10,
+	}
+	target.Rules = defaultRules(target)
+	return Config{
+		TimeoutSeconds:          30,
+		LivenessMultiplier:      3,
+		ReadinessMultiplier:     3,
+		OperationMaxWaitSeconds: 1800,
+		Targets:                 []Target{target},
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name     string
+		modify   func(c *Config)
+		wantErrs []string
+	}{
+		{name: "valid", modify: func(*Config) {}},
+		{
+			name:     "no targets",
+			modify:   func(c *Config) { c.Targets = nil },
+			wantErrs: []string{"nenhum alvo configurado"},
+		},
+		{
+			name:     "duplicate target",
+			modify:   func(c *Config) { c.Targets = append(c.Targets, c.Targets[0]) },
+			wantErrs: []string{"o alvo 'reports' está definido mais de uma vez"},
+		},
+		{
+			name: "several errors are joined",
+			modify: func(c *Config) {
+				c.TimeoutSeconds = 0
+				c.Targets[0].InstanceName = ""
+				c.Targets[0].MinReplicas = 6
+				c.Targets[0].Evaluation = -1
+				c.Targets[0].CooldownScope = "none"
+				c.Targets[0].ScaleDownCooldown = -1
+			},
+			wantErrs: []string{
+				"TIMEOUT_SECONDS deve ser maior que 0",
+				"alvo 'reports': INSTANCE_NAME não pode ser vazio",
+				"alvo 'reports': MIN_REPLICAS (6) não pode ser maior que MAX_REPLICAS (5)",
+				"alvo 'reports': EVALUATION não pode ser negativo",
+				"alvo 'reports': COOLDOWN_SCOPE deve ser opposite ou all, valor atual: 'none'",
+				"alvo 'reports': SCALE_DOWN_COOLDOWN não pode ser negativo",
+			},
+		},
+		{
+			name: "max replicas above the AlloyDB limit",
+			modify: func(c *Config) {
+				c.Targets[0].MaxReplicas = maxReplicasLimit + 1
+			},
+			wantErrs: []string{"MAX_REPLICAS não pode exceder"},
+		},
+		{
+			name: "invalid rules",
+			modify: func(c *Config) {
+				rules := c.Targets[0].Rules
+				rules[1].Name = rules[0].Name
+				rules[1].Aligner = "ALIGN_SOMETHING"
+				rules[0].Scale = 0
+			},
+			wantErrs: []string{
+				"alvo 'reports': regra 'cpu': scale não pode ser 0",
+				"alvo 'reports': regra 'cpu' está definida mais de uma vez",
+				"alvo 'reports': regra 'cpu': aligner 'ALIGN_SOMETHING' inválido",
+			},
+		},
+		{
+			name:     "no rules",
+			modify:   func(c *Config) { c.Targets[0].Rules = nil },
+			wantErrs: []string{"alvo 'reports': nenhuma regra de escala configurada"},
+		},
+		{
+			name: "scale down thresholds",
+			modify: func(c *Config) {
+				c.Targets[0].MemoryScaleDownThreshold = -1
+				c.Targets[0].Rules[0].ScaleDownThreshold = 90
+			},
+			wantErrs: []string{
+				"alvo 'reports': MEMORY_SCALE_DOWN_THRESHOLD não pode ser negativo",
+				"alvo 'reports': regra 'cpu': o limite para reduzir (90) não pode ser maior que o limite para aumentar (70)",
+			},
+		},
+		{
+			name: "tolerance out of range",
+			modify: func(c *Config) {
+				c.Targets[0].TargetTrackingTolerance = 100
+			},
+			wantErrs: []string{"TARGET_TRACKING_TOLERANCE deve estar entre 0 e 100"},
+		},
+		{
+			name: "leader election without lock file",
+			modify: func(c *Config) {
+				c.LeaderElection = LeaderElection{Backend: "file", LeaseSeconds: 15, RenewSeconds: 5}
+			},
+			wantErrs: []string{"LEADER_ELECTION_LOCK_FILE é obrigatório"},
+		},
+		{
+			name: "lease shorter than renew interval",
+			modify: func(c *Config) {
+				c.LeaderElection = LeaderElection{Backend: "kubernetes", LeaseName: "lease", LeaseSeconds: 5, RenewSeconds: 5}
+			},
+			wantErrs: []string{"LEADER_ELECTION_LEASE_SECONDS (5) deve ser maior que LEADER_ELECTION_RENEW_SECONDS (5)"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := validConfig()
+			tt.modify(&c)
+			err := validate(c)
+			if len(tt.wantErrs) == 0 {
+				if err != nil {
+					t.Fatalf("validate() error = %v", err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatalf("validate() error = nil, want %q", tt.wantErrs)
+			}
+			for _, want := range tt.wantErrs {
+				if !strings.Contains(err.Error(), want) {
+					t.Errorf("validate() error = %q, want it to contain %q", err, want)
+				}
+			}
+		})
+	}
+}
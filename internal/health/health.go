@@ -0,0 +1,300 @@
+package health
+
+import (
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/heraque/alloydb-autoscaler/internal/config"
+	"github.com/heraque/alloydb-autoscaler/internal/leader"
+)
+
+// targetState guarda o progresso do loop de um alvo
+type targetState struct {
+	started            time.Time
+	lastCycle          time.Time
+	lastMetricsSuccess time.Time
+	lastMetricsError   string
+	operationRunning   bool
+	paused             string
+}
+
+// apiState guarda o resultado da última chamada a uma API
+type apiState struct {
+	lastSuccess time.Time
+	lastError   string
+	healthy     bool
+	observed    bool
+}
+
+var (
+	mu       sync.Mutex
+	targets  = make(map[string]*targetState)
+	alloyDB  apiState
+	circuits = make(map[string]CircuitStatus)
+)
+
+// TargetStatus é o estado de um alvo reportado pelos endpoints de saúde
+type TargetStatus struct {
+	Healthy            bool       `json:"healthy"`
+	LastCycle          *time.Time `json:"lastCycle,omitempty"`
+	LastMetricsSuccess *time.Time `json:"lastMetricsSuccess,omitempty"`
+	LastMetricsError   string     `json:"lastMetricsError,omitempty"`
+	OperationRunning   bool       `json:"operationRunning,omitempty"`
+	Paused             string     `json:"paused,omitempty"`
+	Reason             string     `json:"reason,omitempty"`
+}
+
+// Status é o corpo das respostas de /healthz e /readyz
+type Status struct {
+	Healthy  bool                     `json:"healthy"`
+	Role     string                   `json:"role,omitempty"`
+	AlloyDB  *APIStatus               `json:"alloydb,omitempty"`
+	Circuits map[string]CircuitStatus `json:"circuits,omitempty"`
+	Targets  map[string]TargetStatus  `json:"targets"`
+}
+
+// CircuitStatus é o estado do circuit breaker de uma API da GCP
+type CircuitStatus struct {
+	State     string    `json:"state"`
+	Since     time.Time `json:"since"`
+	LastError string    `json:"lastError,omitempty"`
+}
+
+// APIStatus descreve a alcançabilidade de uma API
+type APIStatus struct {
+	Reachable   bool       `json:"reachable"`
+	LastSuccess *time.Time `json:"lastSuccess,omitempty"`
+	LastError   string     `json:"lastError,omitempty"`
+}
+
+func state(target string) *targetState {
+	s, ok := targets[target]
+	if !ok {
+		s = &targetState{started: time.Now()}
+		targets[target] = s
+	}
+	return s
+}
+
+// TargetStarted registra o início do loop de um alvo
+func TargetStarted(target string) {
+	mu.Lock()
+	defer mu.Unlock()
+	targets[target] = &targetState{started: time.Now()}
+}
+
+// Forget remove um alvo que deixou de ser gerenciado
+func Forget(target string) {
+	mu.Lock()
+	defer mu.Unlock()
+	delete(targets, target)
+}
+
+// CycleCompleted registra que o loop do alvo concluiu um ciclo
+func CycleCompleted(target string) {
+	mu.Lock()
+	defer mu.Unlock()
+	state(target).lastCycle = time.Now()
+}
+
+// MetricsCollected registra o resultado da coleta de métricas do alvo
+func MetricsCollected(target string, err error) {
+	mu.Lock()
+	defer mu.Unlock()
+	s := state(target)
+	if err != nil {
+		s.lastMetricsError = err.Error()
+		return
+	}
+	s.lastMetricsSuccess = time.Now()
+	s.lastMetricsError = ""
+}
+
+// OperationStarted marca que o alvo está aguardando uma operação de escala,
+// período em que o loop não conclui ciclos
+func OperationStarted(target string) {
+	mu.Lock()
+	defer mu.Unlock()
+	state(target).operationRunning = true
+}
+
+// OperationFinished marca o fim da operação de escala do alvo
+func OperationFinished(target string) {
+	mu.Lock()
+	defer mu.Unlock()
+	s := state(target)
+	s.operationRunning = false
+	s.lastCycle = time.Now()
+}
+
+// Paused registra por que o alvo está sem tomar decisões; reason vazio
+// indica que as decisões foram retomadas
+func Paused(target, reason string) {
+	mu.Lock()
+	defer mu.Unlock()
+	state(target).paused = reason
+}
+
+// CircuitChanged registra a mudança de estado do circuit breaker de uma API
+func CircuitChanged(api, state string, since time.Time, lastError string) {
+	mu.Lock()
+	defer mu.Unlock()
+	circuits[api] = CircuitStatus{State: state, Since: since, LastError: lastError}
+}
+
+// AlloyDBCall registra o resultado de uma chamada à API do AlloyDB.
+// unreachable indica se o erro mostra que a API está inacessível; os demais
+// erros, como um contexto cancelado, 404 ou 409, não alteram a alcançabilidade.
+func AlloyDBCall(err error, unreachable bool) {
+	mu.Lock()
+	defer mu.Unlock()
+	if err != nil {
+		if !unreachable {
+			return
+		}
+		alloyDB.observed = true
+		alloyDB.healthy = false
+		alloyDB.lastError = err.Error()
+		return
+	}
+	alloyDB.observed = true
+	alloyDB.healthy = true
+	alloyDB.lastSuccess = time.Now()
+	alloyDB.lastError = ""
+}
+
+// Liveness falha quando o loop de algum alvo não conclui um ciclo dentro de
+// LivenessMultiplier × CheckInterval, exceto enquanto aguarda uma operação
+func Liveness() Status {
+	cfg := config.Get()
+	return evaluate(cfg, false, func(t config.Target, s *targetState, now time.Time) string {
+		if s.operationRunning {
+			return ""
+		}
+		last := s.lastCycle
+		if last.IsZero() {
+			last = s.started
+		}
+		if now.Sub(last) > window(t, cfg.LivenessMultiplier) {
+			return "no cycle completed within the liveness window"
+		}
+		return ""
+	})
+}
+
+// Readiness exige coleta de métricas bem-sucedida dentro de
+// ReadinessMultiplier × CheckInterval em todos os alvos, a API do AlloyDB
+// alcançável e nenhum circuit breaker aberto. Uma réplica em standby não
+// coleta métricas e é considerada pronta, assim como um alvo pausado enquanto
+// a instância não está READY.
+func Readiness() Status {
+	cfg := config.Get()
+	if !leader.IsLeader() {
+		return evaluate(cfg, false, func(config.Target, *targetState, time.Time) string { return "" })
+	}
+	return evaluate(cfg, true, func(t config.Target, s *targetState, now time.Time) string {
+		if s.paused != "" {
+			return ""
+		}
+		if s.lastMetricsSuccess.IsZero() {
+			return "no successful metrics collection yet"
+		}
+		if now.Sub(s.lastMetricsSuccess) > window(t, cfg.ReadinessMultiplier) {
+			return "no successful metrics collection within the readiness window"
+		}
+		return ""
+	})
+}
+
+func evaluate(cfg config.Config, withAPIs bool, check func(config.Target, *targetState, time.Time) string) Status {
+	mu.Lock()
+	defer mu.Unlock()
+
+	now := time.Now()
+	status := Status{Healthy: true, Role: role(), Targets: make(map[string]TargetStatus, len(cfg.Targets))}
+	for _, t := range cfg.Targets {
+		s := state(t.Name)
+		ts := TargetStatus{
+			LastCycle:          timePtr(s.lastCycle),
+			LastMetricsSuccess: timePtr(s.lastMetricsSuccess),
+			LastMetricsError:   s.lastMetricsError,
+			OperationRunning:   s.operationRunning,
+			Paused:             s.paused,
+		}
+		ts.Reason = check(t, s, now)
+		ts.Healthy = ts.Reason == ""
+		if !ts.Healthy {
+			status.Healthy = false
+		}
+		status.Targets[t.Name] = ts
+	}
+
+	if withAPIs {
+		status.AlloyDB = &APIStatus{
+			Reachable:   alloyDB.healthy,
+			LastSuccess: timePtr(alloyDB.lastSuccess),
+			LastError:   alloyDB.lastError,
+		}
+		if !alloyDB.observed || !alloyDB.healthy {
+			status.Healthy = false
+		}
+
+		for api, circuit := range circuits {
+			if status.Circuits == nil {
+				status.Circuits = make(map[string]CircuitStatus, len(circuits))
+			}
+			status.Circuits[api] = circuit
+			if circuit.State != "closed" {
+				status.Healthy = false
+			}
+		}
+	}
+
+	return status
+}
+
+// role descreve o papel do processo quando a eleição de líder está ativa
+func role() string {
+	switch {
+	case !leader.Enabled():
+		return ""
+	case leader.IsLeader():
+		return "leader"
+	default:
+		return "standby"
+	}
+}
+
+func window(t config.Target, multiplier float64) time.Duration {
+	return time.Duration(multiplier * float64(t.CheckInterval) * float64(time.Second))
+}
+
+func timePtr(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+	return &t
+}
+
+// LivenessHandler responde em /healthz
+func LivenessHandler() http.Handler {
+	return handler(Liveness)
+}
+
+// ReadinessHandler responde em /readyz
+func ReadinessHandler() http.Handler {
+	return handler(Readiness)
+}
+
+func handler(check func() Status) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		status := check()
+		w.Header().Set("Content-Type", "application/json")
+		if !status.Healthy {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+		_ = json.NewEncoder(w).Encode(status)
+	})
+}
@@ -0,0 +1,203 @@
+package health
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/heraque/alloydb-autoscaler/internal/config"
+)
+
+// resetAlloyDB descarta o estado da API do AlloyDB ao fim do teste
+func resetAlloyDB(t *testing.T) {
+	t.Cleanup(func() {
+		mu.Lock()
+		defer mu.Unlock()
+		alloyDB = apiState{}
+	})
+}
+
+func TestAlloyDBCall(t *testing.T) {
+	type call struct {
+		err         error
+		unreachable bool
+	}
+	failure := errors.New("failure")
+	tests := []struct {
+		name          string
+		calls         []call
+		wantObserved  bool
+		wantReachable bool
+		wantError     string
+	}{
+		{
+			name:          "success",
+			calls:         []call{{}},
+			wantObserved:  true,
+			wantReachable: true,
+		},
+		{
+			name:         "unreachable error after a success",
+			calls:        []call{{}, {err: failure, unreachable: true}},
+			wantObserved: true,
+			wantError:    "failure",
+		},
+		{
+			name:          "other error after a success",
+			calls:         []call{{}, {err: failure}},
+			wantObserved:  true,
+			wantReachable: true,
+		},
+		{
+			name:  "only other errors",
+			calls: []call{{err: failure}, {err: failure}},
+		},
+		{
+			name:          "success after an unreachable error",
+			calls:         []call{{err: failure, unreachable: true}, {}},
+			wantObserved:  true,
+			wantReachable: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resetAlloyDB(t)
+			for _, c := range tt.calls {
+				AlloyDBCall(c.err, c.unreachable)
+			}
+
+			mu.Lock()
+			got := alloyDB
+			mu.Unlock()
+			if got.observed != tt.wantObserved || got.healthy != tt.wantReachable || got.lastError != tt.wantError {
+				t.Errorf("state = {observed: %v, healthy: %v, lastError: %q}, want {%v, %v, %q}",
+					got.observed, got.healthy, got.lastError, tt.wantObserved, tt.wantReachable, tt.wantError)
+			}
+		})
+	}
+}
+
+// useTarget configura um único alvo verificado a cada minuto e descarta o
+// estado dele, do AlloyDB e dos circuit breakers ao fim do teste
+func useTarget(t *testing.T) config.Target {
+	previous := config.Get()
+	target := config.Target{Name: t.Name(), CheckInterval: 60}
+	config.Set(config.Config{LivenessMultiplier: 3, ReadinessMultiplier: 3, Targets: []config.Target{target}})
+	resetAlloyDB(t)
+	t.Cleanup(func() {
+		config.Set(previous)
+		Forget(target.Name)
+		mu.Lock()
+		defer mu.Unlock()
+		circuits = make(map[string]CircuitStatus)
+	})
+	return target
+}
+
+func TestHandlers(t *testing.T) {
+	now := time.Now()
+	stale := now.Add(-time.Hour)
+	tests := []struct {
+		name          string
+		state         targetState
+		unreachable   bool
+		circuit       string
+		wantLiveness  int
+		wantReadiness int
+	}{
+		{
+			name:          "healthy",
+			state:         targetState{started: stale, lastCycle: now, lastMetricsSuccess: now},
+			wantLiveness:  http.StatusOK,
+			wantReadiness: http.StatusOK,
+		},
+		{
+			name:          "stale cycle",
+			state:         targetState{started: stale, lastCycle: stale, lastMetricsSuccess: now},
+			wantLiveness:  http.StatusServiceUnavailable,
+			wantReadiness: http.StatusOK,
+		},
+		{
+			name:          "stale cycle while an operation runs",
+			state:         targetState{started: stale, lastCycle: stale, lastMetricsSuccess: now, operationRunning: true},
+			wantLiveness:  http.StatusOK,
+			wantReadiness: http.StatusOK,
+		},
+		{
+			name:          "starting without a cycle",
+			state:         targetState{started: now},
+			wantLiveness:  http.StatusOK,
+			wantReadiness: http.StatusServiceUnavailable,
+		},
+		{
+			name:          "stale metrics",
+			state:         targetState{started: stale, lastCycle: now, lastMetricsSuccess: stale},
+			wantLiveness:  http.StatusOK,
+			wantReadiness: http.StatusServiceUnavailable,
+		},
+		{
+			name:          "paused target without metrics",
+			state:         targetState{started: stale, lastCycle: now, paused: "instance state is MAINTENANCE"},
+			wantLiveness:  http.StatusOK,
+			wantReadiness: http.StatusOK,
+		},
+		{
+			name:          "AlloyDB unreachable",
+			state:         targetState{started: stale, lastCycle: now, lastMetricsSuccess: now},
+			unreachable:   true,
+			wantLiveness:  http.StatusOK,
+			wantReadiness: http.StatusServiceUnavailable,
+		},
+		{
+			name:          "open breaker",
+			state:         targetState{started: stale, lastCycle: now, lastMetricsSuccess: now},
+			circuit:       "open",
+			wantLiveness:  http.StatusOK,
+			wantReadiness: http.StatusServiceUnavailable,
+		},
+		{
+			name:          "closed breaker",
+			state:         targetState{started: stale, lastCycle: now, lastMetricsSuccess: now},
+			circuit:       "closed",
+			wantLiveness:  http.StatusOK,
+			wantReadiness: http.StatusOK,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			target := useTarget(t)
+			mu.Lock()
+			s := tt.state
+			targets[target.Name] = &s
+			mu.Unlock()
+			if tt.unreachable {
+				AlloyDBCall(errors.New("unavailable"), true)
+			} else {
+				AlloyDBCall(nil, false)
+			}
+			if tt.circuit != "" {
+				CircuitChanged("monitoring", tt.circuit, now, "")
+			}
+
+			for _, check := range []struct {
+				path    string
+				handler http.Handler
+				want    int
+			}{
+				{"/healthz", LivenessHandler(), tt.wantLiveness},
+				{"/readyz", ReadinessHandler(), tt.wantReadiness},
+			} {
+				rec := httptest.NewRecorder()
+				check.handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, check.path, nil))
+				if rec.Code != check.want {
+					t.Errorf("%s status = %d, want %d; body %s", check.path, rec.Code, check.want, rec.Body)
+				}
+				if got := rec.Header().Get("Content-Type"); got != "application/json" {
+					t.Errorf("%s Content-Type = %q, want application/json", check.path, got)
+				}
+			}
+		})
+	}
+}
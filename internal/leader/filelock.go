@@ -0,0 +1,72 @@
+//go:build unix
+
+package leader
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// FileLock é um Backend baseado em flock sobre um arquivo local. A liderança
+// dura enquanto o processo mantiver o arquivo aberto, então o ttl é ignorado e
+// o lock é liberado automaticamente se o processo morrer. Serve para testes e
+// para réplicas que compartilham o mesmo host ou volume com suporte a flock.
+type FileLock struct {
+	path string
+
+	mu   sync.Mutex
+	file *os.File
+}
+
+var _ Backend = (*FileLock)(nil)
+
+// NewFileLock cria um FileLock sobre path
+func NewFileLock(path string) *FileLock {
+	return &FileLock{path: path}
+}
+
+func (l *FileLock) Acquire(_ context.Context, identity string, _ time.Duration) (bool, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.file != nil {
+		return true, nil
+	}
+
+	f, err := os.OpenFile(l.path, os.O_RDWR|os.O_CREATE, 0o644)
+	if err != nil {
+		return false, fmt.Errorf("error opening lock file %s: %w", l.path, err)
+	}
+	if err := syscall.Flock(int(f.Fd()), syscall.LOCK_EX|syscall.LOCK_NB); err != nil {
+		f.Close()
+		if errors.Is(err, syscall.EWOULDBLOCK) {
+			return false, nil
+		}
+		return false, fmt.Errorf("error locking %s: %w", l.path, err)
+	}
+
+	// O conteúdo é apenas informativo, para saber quem detém o lock
+	if err := f.Truncate(0); err == nil {
+		_, _ = f.WriteAt([]byte(identity+"\n"), 0)
+	}
+	l.file = f
+	return true, nil
+}
+
+func (l *FileLock) Release(_ context.Context, _ string) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.file == nil {
+		return nil
+	}
+	err := syscall.Flock(int(l.file.Fd()), syscall.LOCK_UN)
+	if closeErr := l.file.Close(); err == nil {
+		err = closeErr
+	}
+	l.file = nil
+	return err
+}
@@ -0,0 +1,27 @@
+//go:build !unix
+
+package leader
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// FileLock não é suportado fora de sistemas unix
+type FileLock struct{}
+
+var _ Backend = (*FileLock)(nil)
+
+// NewFileLock cria um FileLock que sempre falha nesta plataforma
+func NewFileLock(string) *FileLock {
+	return &FileLock{}
+}
+
+func (*FileLock) Acquire(context.Context, string, time.Duration) (bool, error) {
+	return false, errors.New("file lock leader election is only supported on unix systems")
+}
+
+func (*FileLock) Release(context.Context, string) error {
+	return nil
+}
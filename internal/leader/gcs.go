@@ -0,0 +1,121 @@
+package leader
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"google.golang.org/api/googleapi"
+	"google.golang.org/api/option"
+	"google.golang.org/api/storage/v1"
+)
+
+// GCSLeaseStore guarda o lease como um objeto JSON no Cloud Storage. A escrita
+// condicional usa a generation do objeto (ifGenerationMatch), então o bucket
+// não precisa de nenhuma configuração especial além de leitura e escrita para
+// a service account.
+type GCSLeaseStore struct {
+	service *storage.Service
+	bucket  string
+	object  string
+}
+
+var _ LeaseStore = (*GCSLeaseStore)(nil)
+
+// NewGCSLeaseStore cria um GCSLeaseStore para gs://bucket/object
+func NewGCSLeaseStore(ctx context.Context, credentialsFile, bucket, object string) (*GCSLeaseStore, error) {
+	service, err := storage.NewService(ctx, option.WithCredentialsFile(credentialsFile))
+	if err != nil {
+		return nil, fmt.Errorf("error creating Cloud Storage service: %w", err)
+	}
+	return &GCSLeaseStore{service: service, bucket: bucket, object: object}, nil
+}
+
+// gcsLease é o conteúdo do objeto de lease
+type gcsLease struct {
+	Holder          string    `json:"holder"`
+	DurationSeconds int       `json:"leaseDurationSeconds"`
+	AcquireTime     time.Time `json:"acquireTime"`
+	RenewTime       time.Time `json:"renewTime"`
+	Transitions     int       `json:"leaseTransitions"`
+}
+
+func (s *GCSLeaseStore) Get(ctx context.Context) (Lease, error) {
+	resp, err := s.service.Objects.Get(s.bucket, s.object).Context(ctx).Download()
+	if err != nil {
+		if statusCode(err) == http.StatusNotFound {
+			return Lease{}, ErrLeaseNotFound
+		}
+		return Lease{}, fmt.Errorf("error reading lease object: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var gl gcsLease
+	if err := json.NewDecoder(resp.Body).Decode(&gl); err != nil {
+		return Lease{}, fmt.Errorf("error decoding lease object: %w", err)
+	}
+	return Lease{
+		Holder:      gl.Holder,
+		Duration:    time.Duration(gl.DurationSeconds) * time.Second,
+		AcquireTime: gl.AcquireTime,
+		RenewTime:   gl.RenewTime,
+		Transitions: gl.Transitions,
+		Version:     resp.Header.Get("X-Goog-Generation"),
+	}, nil
+}
+
+func (s *GCSLeaseStore) Create(ctx context.Context, lease Lease) error {
+	// generation 0 exige que o objeto ainda não exista
+	return s.write(ctx, lease, 0)
+}
+
+func (s *GCSLeaseStore) Update(ctx context.Context, lease Lease) error {
+	generation, err := strconv.ParseInt(lease.Version, 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid lease generation %q: %w", lease.Version, err)
+	}
+	return s.write(ctx, lease, generation)
+}
+
+func (s *GCSLeaseStore) write(ctx context.Context, lease Lease, generation int64) error {
+	data, err := json.Marshal(gcsLease{
+		Holder:          lease.Holder,
+		DurationSeconds: int(lease.Duration / time.Second),
+		AcquireTime:     lease.AcquireTime,
+		RenewTime:       lease.RenewTime,
+		Transitions:     lease.Transitions,
+	})
+	if err != nil {
+		return fmt.Errorf("error encoding lease object: %w", err)
+	}
+
+	_, err = s.service.Objects.Insert(s.bucket, &storage.Object{
+		Name:         s.object,
+		ContentType:  "application/json",
+		CacheControl: "no-store",
+	}).
+		IfGenerationMatch(generation).
+		Media(bytes.NewReader(data), googleapi.ContentType("application/json")).
+		Context(ctx).
+		Do()
+	if statusCode(err) == http.StatusPreconditionFailed {
+		return ErrLeaseConflict
+	}
+	if err != nil {
+		return fmt.Errorf("error writing lease object: %w", err)
+	}
+	return nil
+}
+
+func statusCode(err error) int {
+	var apiErr *googleapi.Error
+	if errors.As(err, &apiErr) {
+		return apiErr.Code
+	}
+	return 0
+}
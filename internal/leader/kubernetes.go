@@ -0,0 +1,229 @@
+package leader
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// serviceAccountDir é onde o Kubernetes monta o token e o CA do pod
+const serviceAccountDir = "/var/run/secrets/kubernetes.io/serviceaccount"
+
+// microTimeFormat é o formato de MicroTime usado nos campos de tempo do Lease
+const microTimeFormat = "2006-01-02T15:04:05.000000Z07:00"
+
+// KubernetesLeaseStore guarda o lease em um objeto coordination.k8s.io/v1
+// Lease, usando a API REST do cluster com a service account do pod. Requer
+// permissão de get, create e update em leases no namespace.
+type KubernetesLeaseStore struct {
+	client    *http.Client
+	baseURL   string
+	tokenFile string
+	namespace string
+	name      string
+}
+
+var _ LeaseStore = (*KubernetesLeaseStore)(nil)
+
+// NewKubernetesLeaseStore configura o acesso in-cluster ao Lease name. Com
+// namespace vazio usa o namespace do próprio pod.
+func NewKubernetesLeaseStore(namespace, name string) (*KubernetesLeaseStore, error) {
+	host, port := os.Getenv("KUBERNETES_SERVICE_HOST"), os.Getenv("KUBERNETES_SERVICE_PORT")
+	if host == "" || port == "" {
+		return nil, errors.New("kubernetes leader election requires running inside a cluster (KUBERNETES_SERVICE_HOST is not set)")
+	}
+
+	caData, err := os.ReadFile(filepath.Join(serviceAccountDir, "ca.crt"))
+	if err != nil {
+		return nil, fmt.Errorf("error reading service account CA: %w", err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(caData) {
+		return nil, errors.New("error parsing service account CA")
+	}
+
+	if namespace == "" {
+		data, err := os.ReadFile(filepath.Join(serviceAccountDir, "namespace"))
+		if err != nil {
+			return nil, fmt.Errorf("error reading pod namespace: %w", err)
+		}
+		namespace = strings.TrimSpace(string(data))
+	}
+
+	return &KubernetesLeaseStore{
+		client: &http.Client{
+			Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: pool}},
+		},
+		baseURL:   "https://" + net.JoinHostPort(host, port),
+		tokenFile: filepath.Join(serviceAccountDir, "token"),
+		namespace: namespace,
+		name:      name,
+	}, nil
+}
+
+// kubeLease é a representação JSON de um coordination.k8s.io/v1 Lease
+type kubeLease struct {
+	APIVersion string        `json:"apiVersion"`
+	Kind       string        `json:"kind"`
+	Metadata   kubeMetadata  `json:"metadata"`
+	Spec       kubeLeaseSpec `json:"spec"`
+}
+
+type kubeMetadata struct {
+	Name            string `json:"name"`
+	Namespace       string `json:"namespace,omitempty"`
+	ResourceVersion string `json:"resourceVersion,omitempty"`
+}
+
+type kubeLeaseSpec struct {
+	HolderIdentity       *string `json:"holderIdentity,omitempty"`
+	LeaseDurationSeconds *int32  `json:"leaseDurationSeconds,omitempty"`
+	AcquireTime          *string `json:"acquireTime,omitempty"`
+	RenewTime            *string `json:"renewTime,omitempty"`
+	LeaseTransitions     *int32  `json:"leaseTransitions,omitempty"`
+}
+
+func (s *KubernetesLeaseStore) Get(ctx context.Context) (Lease, error) {
+	var kl kubeLease
+	if err := s.do(ctx, http.MethodGet, s.leaseURL(), nil, &kl); err != nil {
+		return Lease{}, err
+	}
+	return fromKubeLease(kl), nil
+}
+
+func (s *KubernetesLeaseStore) Create(ctx context.Context, lease Lease) error {
+	return s.do(ctx, http.MethodPost, s.collectionURL(), s.toKubeLease(lease), nil)
+}
+
+func (s *KubernetesLeaseStore) Update(ctx context.Context, lease Lease) error {
+	return s.do(ctx, http.MethodPut, s.leaseURL(), s.toKubeLease(lease), nil)
+}
+
+func (s *KubernetesLeaseStore) collectionURL() string {
+	return fmt.Sprintf("%s/apis/coordination.k8s.io/v1/namespaces/%s/leases", s.baseURL, url.PathEscape(s.namespace))
+}
+
+func (s *KubernetesLeaseStore) leaseURL() string {
+	return s.collectionURL() + "/" + url.PathEscape(s.name)
+}
+
+// do executa a requisição e traduz 404 e 409 para os erros do LeaseStore
+func (s *KubernetesLeaseStore) do(ctx context.Context, method, endpoint string, body, out any) error {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return fmt.Errorf("error encoding lease: %w", err)
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, endpoint, reader)
+	if err != nil {
+		return err
+	}
+	// O token projetado é rotacionado pelo kubelet, então é relido a cada chamada
+	token, err := os.ReadFile(s.tokenFile)
+	if err != nil {
+		return fmt.Errorf("error reading service account token: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+strings.TrimSpace(string(token)))
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("error calling kubernetes API: %w", err)
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		return ErrLeaseNotFound
+	case resp.StatusCode == http.StatusConflict:
+		return ErrLeaseConflict
+	case resp.StatusCode >= 300:
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("kubernetes API returned %s: %s", resp.Status, strings.TrimSpace(string(msg)))
+	}
+
+	if out != nil {
+		if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+			return fmt.Errorf("error decoding lease: %w", err)
+		}
+	}
+	return nil
+}
+
+func (s *KubernetesLeaseStore) toKubeLease(lease Lease) kubeLease {
+	holder := lease.Holder
+	duration := int32(lease.Duration / time.Second)
+	transitions := int32(lease.Transitions)
+	return kubeLease{
+		APIVersion: "coordination.k8s.io/v1",
+		Kind:       "Lease",
+		Metadata: kubeMetadata{
+			Name:            s.name,
+			Namespace:       s.namespace,
+			ResourceVersion: lease.Version,
+		},
+		Spec: kubeLeaseSpec{
+			HolderIdentity:       &holder,
+			LeaseDurationSeconds: &duration,
+			AcquireTime:          formatMicroTime(lease.AcquireTime),
+			RenewTime:            formatMicroTime(lease.RenewTime),
+			LeaseTransitions:     &transitions,
+		},
+	}
+}
+
+func fromKubeLease(kl kubeLease) Lease {
+	lease := Lease{
+		AcquireTime: parseMicroTime(kl.Spec.AcquireTime),
+		RenewTime:   parseMicroTime(kl.Spec.RenewTime),
+		Version:     kl.Metadata.ResourceVersion,
+	}
+	if kl.Spec.HolderIdentity != nil {
+		lease.Holder = *kl.Spec.HolderIdentity
+	}
+	if kl.Spec.LeaseDurationSeconds != nil {
+		lease.Duration = time.Duration(*kl.Spec.LeaseDurationSeconds) * time.Second
+	}
+	if kl.Spec.LeaseTransitions != nil {
+		lease.Transitions = int(*kl.Spec.LeaseTransitions)
+	}
+	return lease
+}
+
+func formatMicroTime(t time.Time) *string {
+	if t.IsZero() {
+		return nil
+	}
+	value := t.UTC().Format(microTimeFormat)
+	return &value
+}
+
+func parseMicroTime(value *string) time.Time {
+	if value == nil {
+		return time.Time{}
+	}
+	t, err := time.Parse(time.RFC3339Nano, *value)
+	if err != nil {
+		return time.Time{}
+	}
+	return t
+}
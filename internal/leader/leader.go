@@ -0,0 +1,188 @@
+package leader
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sync/atomic"
+	"time"
+
+	"github.com/heraque/alloydb-autoscaler/internal/config"
+	"github.com/heraque/alloydb-autoscaler/internal/log"
+	"github.com/heraque/alloydb-autoscaler/internal/telemetry"
+)
+
+// Backend concede liderança exclusiva a uma identidade
+type Backend interface {
+	// Acquire obtém ou renova a liderança para identity por até ttl e informa
+	// se identity é o líder após a chamada
+	Acquire(ctx context.Context, identity string, ttl time.Duration) (bool, error)
+	// Release abre mão da liderança, se identity a detiver
+	Release(ctx context.Context, identity string) error
+}
+
+var (
+	// enabled indica que há um Elector neste processo; sem ele o processo é
+	// sempre o líder
+	enabled atomic.Bool
+	leading atomic.Bool
+)
+
+// IsLeader informa se este processo deve avaliar métricas e alterar réplicas
+func IsLeader() bool {
+	return !enabled.Load() || leading.Load()
+}
+
+// Enabled informa se a eleição de líder está ativa neste processo
+func Enabled() bool {
+	return enabled.Load()
+}
+
+// NewBackend cria o Backend configurado em le
+func NewBackend(ctx context.Context, le config.LeaderElection, credentialsFile string) (Backend, error) {
+	switch le.Backend {
+	case "file":
+		return NewFileLock(le.LockFile), nil
+	case "kubernetes":
+		store, err := NewKubernetesLeaseStore(le.Namespace, le.LeaseName)
+		if err != nil {
+			return nil, err
+		}
+		return NewLeaseBackend(store), nil
+	case "gcs":
+		store, err := NewGCSLeaseStore(ctx, credentialsFile, le.Bucket, le.LeaseName)
+		if err != nil {
+			return nil, err
+		}
+		return NewLeaseBackend(store), nil
+	default:
+		return nil, fmt.Errorf("unknown leader election backend %q", le.Backend)
+	}
+}
+
+// Elector disputa a liderança em um Backend e a renova periodicamente
+type Elector struct {
+	backend       Backend
+	identity      string
+	leaseDuration time.Duration
+	renewInterval time.Duration
+}
+
+// NewElector cria o Elector do processo. A partir daqui IsLeader retorna false
+// até a liderança ser obtida por Run.
+func NewElector(backend Backend, identity string, leaseDuration, renewInterval time.Duration) *Elector {
+	if identity == "" {
+		identity = DefaultIdentity()
+	}
+	enabled.Store(true)
+	setLeading(false)
+	return &Elector{
+		backend:       backend,
+		identity:      identity,
+		leaseDuration: leaseDuration,
+		renewInterval: renewInterval,
+	}
+}
+
+// DefaultIdentity usa POD_NAME quando definido, ou hostname e PID
+func DefaultIdentity() string {
+	if name := os.Getenv("POD_NAME"); name != "" {
+		return name
+	}
+	host, err := os.Hostname()
+	if err != nil {
+		host = "unknown"
+	}
+	return fmt.Sprintf("%s-%d", host, os.Getpid())
+}
+
+// Identity retorna a identidade usada na disputa
+func (e *Elector) Identity() string {
+	return e.identity
+}
+
+// Run disputa e renova a liderança a cada renewInterval até o contexto ser
+// cancelado. Se a renovação falhar, o processo deixa de se considerar líder
+// antes de o lease expirar para os demais.
+func (e *Elector) Run(ctx context.Context) {
+	log.Info().
+		Str("component", "leader").
+		Str("action", "start").
+		Str("identity", e.identity).
+		Dur("leaseDuration", e.leaseDuration).
+		Dur("renewInterval", e.renewInterval).
+		Msg("Starting leader election")
+
+	var lastRenew time.Time
+	for {
+		attemptCtx, cancel := context.WithTimeout(ctx, e.renewInterval)
+		ok, err := e.backend.Acquire(attemptCtx, e.identity, e.leaseDuration)
+		cancel()
+		if ctx.Err() != nil {
+			return
+		}
+
+		now := time.Now()
+		if err != nil {
+			log.Warn().
+				Str("component", "leader").
+				Str("action", "renew").
+				Str("identity", e.identity).
+				Err(err).
+				Msg("Failed to acquire or renew leadership")
+			// Mantém a liderança apenas se o próximo intervalo ainda couber no lease
+			ok = leading.Load() && now.Add(e.renewInterval).Before(lastRenew.Add(e.leaseDuration))
+		} else if ok {
+			lastRenew = now
+		}
+		e.transition(ok)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(e.renewInterval):
+		}
+	}
+}
+
+// Resign libera a liderança para que outra réplica assuma sem esperar o lease expirar
+func (e *Elector) Resign(ctx context.Context) error {
+	wasLeader := leading.Load()
+	setLeading(false)
+	if err := e.backend.Release(ctx, e.identity); err != nil {
+		return fmt.Errorf("error releasing leadership: %w", err)
+	}
+	if wasLeader {
+		log.Info().
+			Str("component", "leader").
+			Str("action", "resign").
+			Str("identity", e.identity).
+			Msg("Leadership released")
+	}
+	return nil
+}
+
+func (e *Elector) transition(ok bool) {
+	if ok == leading.Load() {
+		return
+	}
+	setLeading(ok)
+	if ok {
+		log.Info().
+			Str("component", "leader").
+			Str("action", "acquire").
+			Str("identity", e.identity).
+			Msg("Leadership acquired, evaluating targets")
+		return
+	}
+	log.Warn().
+		Str("component", "leader").
+		Str("action", "lose").
+		Str("identity", e.identity).
+		Msg("Leadership lost, standing by")
+}
+
+func setLeading(ok bool) {
+	leading.Store(ok)
+	telemetry.SetLeader(ok)
+}
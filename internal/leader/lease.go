@@ -0,0 +1,127 @@
+package leader
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrLeaseNotFound indica que o lease ainda não existe no LeaseStore
+	ErrLeaseNotFound = errors.New("lease not found")
+	// ErrLeaseConflict indica que o lease foi alterado por outro processo
+	// desde a leitura, ou já existia ao tentar criá-lo
+	ErrLeaseConflict = errors.New("lease was modified concurrently")
+)
+
+// Lease é o registro de liderança guardado em um LeaseStore
+type Lease struct {
+	Holder      string
+	Duration    time.Duration
+	AcquireTime time.Time
+	RenewTime   time.Time
+	Transitions int
+	// Version identifica a revisão lida e é usada como pré-condição na escrita
+	// (resourceVersion no Kubernetes, generation no GCS)
+	Version string
+}
+
+// LeaseStore guarda um único Lease com escrita condicional
+type LeaseStore interface {
+	// Get retorna o lease atual ou ErrLeaseNotFound
+	Get(ctx context.Context) (Lease, error)
+	// Create cria o lease; retorna ErrLeaseConflict se ele já existir
+	Create(ctx context.Context, lease Lease) error
+	// Update grava o lease se ele ainda estiver na revisão lease.Version;
+	// caso contrário retorna ErrLeaseConflict
+	Update(ctx context.Context, lease Lease) error
+}
+
+// LeaseBackend implementa Backend sobre um LeaseStore. A expiração é medida
+// pelo relógio local a partir do momento em que uma mudança no lease foi
+// observada, como no client-go, para não depender de relógios sincronizados.
+type LeaseBackend struct {
+	store LeaseStore
+	// Now permite controlar o relógio usado para expirar leases
+	Now func() time.Time
+
+	mu         sync.Mutex
+	observed   string
+	observedAt time.Time
+}
+
+var _ Backend = (*LeaseBackend)(nil)
+
+// NewLeaseBackend cria um Backend sobre store
+func NewLeaseBackend(store LeaseStore) *LeaseBackend {
+	return &LeaseBackend{store: store, Now: time.Now}
+}
+
+func (b *LeaseBackend) Acquire(ctx context.Context, identity string, ttl time.Duration) (bool, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := b.Now()
+	current, err := b.store.Get(ctx)
+	if errors.Is(err, ErrLeaseNotFound) {
+		err = b.store.Create(ctx, Lease{Holder: identity, Duration: ttl, AcquireTime: now, RenewTime: now})
+		return writeResult(err)
+	}
+	if err != nil {
+		return false, err
+	}
+
+	if current.Version != b.observed {
+		b.observed = current.Version
+		b.observedAt = now
+	}
+	held := current.Holder != "" && current.Holder != identity
+	if held && now.Before(b.observedAt.Add(current.Duration)) {
+		return false, nil
+	}
+
+	next := current
+	if current.Holder != identity {
+		next.Holder = identity
+		next.AcquireTime = now
+		next.Transitions++
+	}
+	next.Duration = ttl
+	next.RenewTime = now
+	return writeResult(b.store.Update(ctx, next))
+}
+
+func (b *LeaseBackend) Release(ctx context.Context, identity string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	current, err := b.store.Get(ctx)
+	if errors.Is(err, ErrLeaseNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if current.Holder != identity {
+		return nil
+	}
+	current.Holder = ""
+	current.RenewTime = b.Now()
+	if err := b.store.Update(ctx, current); err != nil && !errors.Is(err, ErrLeaseConflict) {
+		return err
+	}
+	return nil
+}
+
+// writeResult converte o resultado de uma escrita condicional: perder a
+// disputa não é um erro, apenas significa que outro processo é o líder
+func writeResult(err error) (bool, error) {
+	if errors.Is(err, ErrLeaseConflict) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
@@ -0,0 +1,157 @@
+package leader
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestLeaseBackendAcquire(t *testing.T) {
+	const ttl = 15 * time.Second
+
+	// Cada passo avança o relógio compartilhado por elapsed e executa a ação
+	// com a réplica identity
+	type step struct {
+		elapsed  time.Duration
+		identity string
+		release  bool
+		want     bool
+	}
+	tests := []struct {
+		name            string
+		steps           []step
+		wantHolder      string
+		wantTransitions int
+	}{
+		{
+			name:       "first replica creates the lease",
+			steps:      []step{{identity: "a", want: true}},
+			wantHolder: "a",
+		},
+		{
+			name: "holder renews, other replica waits",
+			steps: []step{
+				{identity: "a", want: true},
+				{identity: "b", want: false},
+				{elapsed: 10 * time.Second, identity: "a", want: true},
+				{elapsed: 10 * time.Second, identity: "b", want: false},
+			},
+			wantHolder: "a",
+		},
+		{
+			name: "lease expires without renewal",
+			steps: []step{
+				{identity: "a", want: true},
+				{identity: "b", want: false},
+				{elapsed: 14 * time.Second, identity: "b", want: false},
+				{elapsed: time.Second, identity: "b", want: true},
+				{identity: "a", want: false},
+			},
+			wantHolder:      "b",
+			wantTransitions: 1,
+		},
+		{
+			name: "expiry counts from the last observed renewal",
+			steps: []step{
+				{identity: "a", want: true},
+				{identity: "b", want: false},
+				{elapsed: 10 * time.Second, identity: "a", want: true},
+				{elapsed: 10 * time.Second, identity: "b", want: false},
+				{elapsed: 14 * time.Second, identity: "b", want: false},
+				{elapsed: time.Second, identity: "b", want: true},
+			},
+			wantHolder:      "b",
+			wantTransitions: 1,
+		},
+		{
+			name: "release hands over immediately",
+			steps: []step{
+				{identity: "a", want: true},
+				{identity: "b", want: false},
+				{identity: "a", release: true},
+				{identity: "b", want: true},
+			},
+			wantHolder:      "b",
+			wantTransitions: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			store := NewMemoryLeaseStore()
+			now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+			backends := make(map[string]*LeaseBackend)
+			backend := func(identity string) *LeaseBackend {
+				if b, ok := backends[identity]; ok {
+					return b
+				}
+				b := NewLeaseBackend(store)
+				b.Now = func() time.Time { return now }
+				backends[identity] = b
+				return b
+			}
+
+			for i, s := range tt.steps {
+				now = now.Add(s.elapsed)
+				b := backend(s.identity)
+				if s.release {
+					if err := b.Release(ctx, s.identity); err != nil {
+						t.Fatalf("step %d: Release(%s) error = %v", i, s.identity, err)
+					}
+					continue
+				}
+				got, err := b.Acquire(ctx, s.identity, ttl)
+				if err != nil {
+					t.Fatalf("step %d: Acquire(%s) error = %v", i, s.identity, err)
+				}
+				if got != s.want {
+					t.Fatalf("step %d: Acquire(%s) = %v, want %v", i, s.identity, got, s.want)
+				}
+			}
+
+			lease, err := store.Get(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if lease.Holder != tt.wantHolder || lease.Transitions != tt.wantTransitions {
+				t.Errorf("lease holder/transitions = %s/%d, want %s/%d", lease.Holder, lease.Transitions, tt.wantHolder, tt.wantTransitions)
+			}
+		})
+	}
+}
+
+func TestLeaseBackendConcurrentTakeover(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryLeaseStore()
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	clock := func() time.Time { return now }
+
+	a, b, c := NewLeaseBackend(store), NewLeaseBackend(store), NewLeaseBackend(store)
+	a.Now, b.Now, c.Now = clock, clock, clock
+	if ok, err := a.Acquire(ctx, "a", time.Second); !ok || err != nil {
+		t.Fatalf("Acquire(a) = %v, %v", ok, err)
+	}
+	for _, backend := range []*LeaseBackend{b, c} {
+		if ok, _ := backend.Acquire(ctx, "other", time.Second); ok {
+			t.Fatal("lease taken before expiring")
+		}
+	}
+
+	// b e c leem o mesmo lease expirado; só a primeira escrita vence
+	now = now.Add(2 * time.Second)
+	stale, err := store.Get(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ok, err := b.Acquire(ctx, "b", time.Second); !ok || err != nil {
+		t.Fatalf("Acquire(b) = %v, %v", ok, err)
+	}
+	stale.Holder = "c"
+	if err := store.Update(ctx, stale); !errors.Is(err, ErrLeaseConflict) {
+		t.Errorf("Update with a stale version error = %v, want ErrLeaseConflict", err)
+	}
+	if ok, err := c.Acquire(ctx, "c", time.Second); ok || err != nil {
+		t.Errorf("Acquire(c) = %v, %v, want false without error", ok, err)
+	}
+}
@@ -0,0 +1,58 @@
+package leader
+
+import (
+	"context"
+	"strconv"
+	"sync"
+)
+
+// MemoryLeaseStore é um LeaseStore em memória, usado localmente e em testes
+// no lugar do Kubernetes ou do GCS. Vários LeaseBackend podem compartilhar a
+// mesma instância para simular réplicas concorrentes.
+type MemoryLeaseStore struct {
+	mu       sync.Mutex
+	lease    *Lease
+	revision int
+}
+
+var _ LeaseStore = (*MemoryLeaseStore)(nil)
+
+// NewMemoryLeaseStore cria um MemoryLeaseStore vazio
+func NewMemoryLeaseStore() *MemoryLeaseStore {
+	return &MemoryLeaseStore{}
+}
+
+func (s *MemoryLeaseStore) Get(_ context.Context) (Lease, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.lease == nil {
+		return Lease{}, ErrLeaseNotFound
+	}
+	return *s.lease, nil
+}
+
+func (s *MemoryLeaseStore) Create(_ context.Context, lease Lease) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.lease != nil {
+		return ErrLeaseConflict
+	}
+	s.store(lease)
+	return nil
+}
+
+func (s *MemoryLeaseStore) Update(_ context.Context, lease Lease) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.lease == nil || s.lease.Version != lease.Version {
+		return ErrLeaseConflict
+	}
+	s.store(lease)
+	return nil
+}
+
+func (s *MemoryLeaseStore) store(lease Lease) {
+	s.revision++
+	lease.Version = strconv.Itoa(s.revision)
+	s.lease = &lease
+}
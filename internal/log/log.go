@@ -27,6 +27,7 @@ var (
 		"level",
 		"component",
 		"action",
+		"target",
 		"cycle",
 		"instance",
 		"cluster",
@@ -156,6 +157,21 @@ func setLoggingLevel(level string) {
 	}
 }
 
+// SetLevel altera o nível de log em tempo de execução
+func SetLevel(level string) {
+	if lvl, ok := loggerLevel[strings.ToLower(level)]; ok && lvl != zerolog.GlobalLevel() {
+		zerolog.SetGlobalLevel(lvl)
+		logger.Info().
+			Str("currentLevel", lvl.String()).
+			Msg("logging level changed")
+	}
+}
+
+// Flush garante que os logs já escritos cheguem ao destino antes de encerrar
+func Flush() {
+	_ = os.Stderr.Sync()
+}
+
 func Info() *zerolog.Event  { return logger.Info() }
 func Warn() *zerolog.Event  { return logger.Warn() }
 func Debug() *zerolog.Event { return logger.Debug() }
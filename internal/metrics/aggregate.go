@@ -0,0 +1,71 @@
+package metrics
+
+import (
+	"fmt"
+	"math"
+	"sort"
+
+	"cloud.google.com/go/monitoring/apiv3/v2/monitoringpb"
+	"github.com/heraque/alloydb-autoscaler/internal/config"
+	"github.com/rs/zerolog"
+)
+
+// seriesFilter seleciona as séries de metricType da instância do alvo. Nomes
+// de instância só são únicos dentro do cluster, por isso o filtro inclui o
+// projeto e o cluster.
+func seriesFilter(target config.Target, metricType string) string {
+	return fmt.Sprintf(`metric.type = "%s" AND project = "%s" AND resource.labels.cluster_id = "%s" AND resource.labels.instance_id = "%s"`,
+		metricType, target.GCPProject, target.ClusterName, target.InstanceName)
+}
+
+// seriesNode devolve o node_id de uma série, vazio em séries da instância
+func seriesNode(ts *monitoringpb.TimeSeries) string {
+	if node := ts.GetResource().GetLabels()["node_id"]; node != "" {
+		return node
+	}
+	return ts.GetMetric().GetLabels()["node_id"]
+}
+
+// aggregateNodes combina os valores de cada nó conforme a agregação da regra
+func aggregateNodes(values []float64, aggregation string) float64 {
+	switch aggregation {
+	case config.AggregationMean:
+		var sum float64
+		for _, v := range values {
+			sum += v
+		}
+		return sum / float64(len(values))
+	case config.AggregationP95:
+		return percentile(values, 95)
+	default:
+		result := math.Inf(-1)
+		for _, v := range values {
+			result = max(result, v)
+		}
+		return result
+	}
+}
+
+// percentile calcula o percentil p de values por interpolação linear entre
+// os dois valores mais próximos
+func percentile(values []float64, p float64) float64 {
+	sorted := append([]float64(nil), values...)
+	sort.Float64s(sorted)
+	rank := p / 100 * float64(len(sorted)-1)
+	lower := int(math.Floor(rank))
+	upper := int(math.Ceil(rank))
+	return sorted[lower] + (sorted[upper]-sorted[lower])*(rank-float64(lower))
+}
+
+// nodeValues monta um objeto de log com o valor de cada nó
+func nodeValues(nodes []NodeValue) *zerolog.Event {
+	dict := zerolog.Dict()
+	for i, nv := range nodes {
+		node := nv.Node
+		if node == "" {
+			node = fmt.Sprintf("series-%d", i)
+		}
+		dict.Float64(node, math.Round(nv.Value*100)/100)
+	}
+	return dict
+}
@@ -0,0 +1,49 @@
+package metrics
+
+import (
+	"math"
+	"testing"
+
+	"github.com/heraque/alloydb-autoscaler/internal/config"
+)
+
+func TestAggregateNodes(t *testing.T) {
+	tests := []struct {
+		name        string
+		values      []float64
+		aggregation string
+		want        float64
+	}{
+		{name: "max", values: []float64{40, 90, 10}, aggregation: config.AggregationMax, want: 90},
+		{name: "max of negatives", values: []float64{-3, -1, -2}, aggregation: config.AggregationMax, want: -1},
+		{name: "mean", values: []float64{40, 90, 20}, aggregation: config.AggregationMean, want: 50},
+		{name: "p95 single node", values: []float64{42}, aggregation: config.AggregationP95, want: 42},
+		{name: "p95 two nodes", values: []float64{10, 90}, aggregation: config.AggregationP95, want: 86},
+		{name: "p95 interpolates unsorted values", values: []float64{50, 10, 40, 20, 30}, aggregation: config.AggregationP95, want: 48},
+		{name: "p95 of 21 nodes", values: sequence(21), aggregation: config.AggregationP95, want: 19},
+		{name: "p95 ignores a single outlier", values: append(sequence(20), 1000), aggregation: config.AggregationP95, want: 19},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			values := append([]float64(nil), tt.values...)
+			got := aggregateNodes(tt.values, tt.aggregation)
+			if math.Abs(got-tt.want) > 1e-9 {
+				t.Errorf("aggregateNodes(%v, %s) = %g, want %g", tt.values, tt.aggregation, got, tt.want)
+			}
+			for i := range values {
+				if values[i] != tt.values[i] {
+					t.Fatalf("aggregateNodes reordered its input: %v", tt.values)
+				}
+			}
+		})
+	}
+}
+
+// sequence devolve 0, 1, ..., n-1
+func sequence(n int) []float64 {
+	values := make([]float64, n)
+	for i := range values {
+		values[i] = float64(i)
+	}
+	return values
+}
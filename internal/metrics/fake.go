@@ -0,0 +1,138 @@
+package metrics
+
+import (
+	"context"
+	"regexp"
+	"sync"
+	"time"
+
+	"cloud.google.com/go/monitoring/apiv3/v2/monitoringpb"
+	"google.golang.org/genproto/googleapis/api/metric"
+	"google.golang.org/genproto/googleapis/api/monitoredres"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+var metricTypeFilter = regexp.MustCompile(`metric\.type\s*=\s*"([^"]+)"`)
+
+// fakeResponse é uma resposta roteirizada do FakeSource
+type fakeResponse struct {
+	series []*monitoringpb.TimeSeries
+	err    error
+}
+
+// FakeSource é uma MetricSource em memória que simula o Cloud Monitoring.
+// Respostas roteirizadas com Enqueue são consumidas em ordem; quando não há
+// nenhuma, as séries fixadas com SetSeries são retornadas. Tipos de métrica
+// sem séries configuradas retornam uma lista vazia, como ocorre quando não há
+// dados na janela consultada.
+type FakeSource struct {
+	mu       sync.Mutex
+	series   map[string][]*monitoringpb.TimeSeries
+	scripts  map[string][]fakeResponse
+	requests []*monitoringpb.ListTimeSeriesRequest
+}
+
+var _ MetricSource = (*FakeSource)(nil)
+
+// NewFakeSource cria um FakeSource sem séries configuradas
+func NewFakeSource() *FakeSource {
+	return &FakeSource{
+		series:  make(map[string][]*monitoringpb.TimeSeries),
+		scripts: make(map[string][]fakeResponse),
+	}
+}
+
+// SetSeries fixa as séries retornadas para o tipo de métrica
+func (f *FakeSource) SetSeries(metricType string, series ...*monitoringpb.TimeSeries) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.series[metricType] = series
+}
+
+// SetMissing remove as séries do tipo de métrica, simulando ausência de dados
+func (f *FakeSource) SetMissing(metricType string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	delete(f.series, metricType)
+}
+
+// Enqueue roteiriza a próxima resposta para o tipo de métrica
+func (f *FakeSource) Enqueue(metricType string, series ...*monitoringpb.TimeSeries) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.scripts[metricType] = append(f.scripts[metricType], fakeResponse{series: series})
+}
+
+// EnqueueError roteiriza um erro como próxima resposta para o tipo de métrica
+func (f *FakeSource) EnqueueError(metricType string, err error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.scripts[metricType] = append(f.scripts[metricType], fakeResponse{err: err})
+}
+
+// Requests retorna as requisições recebidas, na ordem
+func (f *FakeSource) Requests() []*monitoringpb.ListTimeSeriesRequest {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]*monitoringpb.ListTimeSeriesRequest(nil), f.requests...)
+}
+
+func (f *FakeSource) ListTimeSeries(ctx context.Context, req *monitoringpb.ListTimeSeriesRequest) ([]*monitoringpb.TimeSeries, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.requests = append(f.requests, req)
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	metricType := ""
+	if m := metricTypeFilter.FindStringSubmatch(req.GetFilter()); m != nil {
+		metricType = m[1]
+	}
+
+	if queued := f.scripts[metricType]; len(queued) > 0 {
+		f.scripts[metricType] = queued[1:]
+		return queued[0].series, queued[0].err
+	}
+	return f.series[metricType], nil
+}
+
+// DoubleSeries monta uma série com valores double, do mais recente para o mais antigo,
+// espaçados de um minuto. resourceLabels identifica a série (ex.: node_id).
+func DoubleSeries(resourceLabels map[string]string, values ...float64) *monitoringpb.TimeSeries {
+	typed := make([]*monitoringpb.TypedValue, len(values))
+	for i, v := range values {
+		typed[i] = &monitoringpb.TypedValue{Value: &monitoringpb.TypedValue_DoubleValue{DoubleValue: v}}
+	}
+	return TypedSeries(resourceLabels, metric.MetricDescriptor_DOUBLE, typed...)
+}
+
+// Int64Series monta uma série com valores int64, do mais recente para o mais antigo
+func Int64Series(resourceLabels map[string]string, values ...int64) *monitoringpb.TimeSeries {
+	typed := make([]*monitoringpb.TypedValue, len(values))
+	for i, v := range values {
+		typed[i] = &monitoringpb.TypedValue{Value: &monitoringpb.TypedValue_Int64Value{Int64Value: v}}
+	}
+	return TypedSeries(resourceLabels, metric.MetricDescriptor_INT64, typed...)
+}
+
+// TypedSeries monta uma série com valores tipados arbitrários, do mais recente para o mais antigo
+func TypedSeries(resourceLabels map[string]string, valueType metric.MetricDescriptor_ValueType, values ...*monitoringpb.TypedValue) *monitoringpb.TimeSeries {
+	now := time.Now()
+	points := make([]*monitoringpb.Point, len(values))
+	for i, v := range values {
+		end := now.Add(-time.Duration(i) * time.Minute)
+		points[i] = &monitoringpb.Point{
+			Interval: &monitoringpb.TimeInterval{
+				StartTime: timestamppb.New(end.Add(-time.Minute)),
+				EndTime:   timestamppb.New(end),
+			},
+			Value: v,
+		}
+	}
+	return &monitoringpb.TimeSeries{
+		Resource:  &monitoredres.MonitoredResource{Labels: resourceLabels},
+		ValueType: valueType,
+		Points:    points,
+	}
+}
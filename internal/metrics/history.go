@@ -0,0 +1,118 @@
+package metrics
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"cloud.google.com/go/monitoring/apiv3/v2/monitoringpb"
+	"github.com/heraque/alloydb-autoscaler/internal/alloydb"
+	"github.com/heraque/alloydb-autoscaler/internal/config"
+	"google.golang.org/protobuf/types/known/durationpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// historyAlignment é a resolução do histórico usado na previsão
+const historyAlignment = time.Hour
+
+// HistoryPoint is one aligned value of a metric history
+type HistoryPoint struct {
+	Time  time.Time
+	Value float64
+}
+
+// QueryHistory returns the rule's history between from and to as hourly
+// means, converted like the live value. Series with the same timestamp are
+// combined with the rule's node aggregation, as in a live check.
+func QueryHistory(ctx context.Context, source MetricSource, snapshot alloydb.InstanceSnapshot, target config.Target, rule config.Rule, from, to time.Time) ([]HistoryPoint, error) {
+	series, err := listHistory(ctx, source, target, rule.Metric, rule.Reducer, from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	evaluator := newRuleEvaluator(source, snapshot, target)
+	byTime := make(map[int64][]float64)
+	for _, ts := range series {
+		for _, point := range ts.Points {
+			raw, err := pointValue(point)
+			if err != nil {
+				return nil, err
+			}
+			value, ok, err := evaluator.convert(ctx, rule, raw, false)
+			if err != nil {
+				return nil, err
+			}
+			if !ok {
+				return nil, nil
+			}
+			key := point.GetInterval().GetEndTime().AsTime().Unix()
+			byTime[key] = append(byTime[key], value*rule.Scale)
+		}
+	}
+	combined := make(map[int64]float64, len(byTime))
+	for key, values := range byTime {
+		combined[key] = aggregateNodes(values, rule.NodeAggregation)
+	}
+	return sortedHistory(combined), nil
+}
+
+// QueryNodeCountHistory returns the number of read pool nodes over time,
+// summing the series of metricType
+func QueryNodeCountHistory(ctx context.Context, source MetricSource, target config.Target, metricType string, from, to time.Time) ([]HistoryPoint, error) {
+	series, err := listHistory(ctx, source, target, metricType, "REDUCE_SUM", from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	summed := make(map[int64]float64)
+	for _, ts := range series {
+		for _, point := range ts.Points {
+			value, err := pointValue(point)
+			if err != nil {
+				return nil, err
+			}
+			summed[point.GetInterval().GetEndTime().AsTime().Unix()] += math.Round(value)
+		}
+	}
+	return sortedHistory(summed), nil
+}
+
+// listHistory consulta médias horárias de metricType no intervalo
+func listHistory(ctx context.Context, source MetricSource, target config.Target, metricType, reducer string, from, to time.Time) ([]*monitoringpb.TimeSeries, error) {
+	req := &monitoringpb.ListTimeSeriesRequest{
+		Name:   fmt.Sprintf("projects/%s", target.GCPProject),
+		Filter: seriesFilter(target, metricType),
+		Interval: &monitoringpb.TimeInterval{
+			StartTime: timestamppb.New(from),
+			EndTime:   timestamppb.New(to),
+		},
+		Aggregation: &monitoringpb.Aggregation{
+			AlignmentPeriod:  durationpb.New(historyAlignment),
+			PerSeriesAligner: monitoringpb.Aggregation_ALIGN_MEAN,
+		},
+		View: monitoringpb.ListTimeSeriesRequest_FULL,
+	}
+	if reducer != "" {
+		req.Aggregation.CrossSeriesReducer = monitoringpb.Aggregation_Reducer(monitoringpb.Aggregation_Reducer_value[reducer])
+	}
+
+	series, err := source.ListTimeSeries(ctx, req)
+	if err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			return nil, fmt.Errorf("timeout querying history of metric %s: %w", metricType, err)
+		}
+		return nil, err
+	}
+	return series, nil
+}
+
+func sortedHistory(values map[int64]float64) []HistoryPoint {
+	points := make([]HistoryPoint, 0, len(values))
+	for ts, value := range values {
+		points = append(points, HistoryPoint{Time: time.Unix(ts, 0), Value: value})
+	}
+	sort.Slice(points, func(i, j int) bool { return points[i].Time.Before(points[j].Time) })
+	return points
+}
@@ -0,0 +1,112 @@
+package metrics
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sync"
+
+	"github.com/heraque/alloydb-autoscaler/internal/config"
+	"github.com/heraque/alloydb-autoscaler/internal/log"
+)
+
+// memoryMismatchTolerance é a diferença relativa a partir da qual duas
+// fontes de memória total são consideradas divergentes
+const memoryMismatchTolerance = 0.05
+
+var (
+	memoryWarningsMu sync.Mutex
+	// memoryWarnings guarda o último aviso de memória de cada alvo, para que
+	// a mesma divergência seja registrada uma vez e não a cada verificação
+	memoryWarnings = make(map[string]string)
+)
+
+// totalMemory resolve a memória de cada nó, em GB, na ordem: MemoryTotalGB
+// do alvo, a métrica MemoryTotalMetric e o catálogo de formatos de máquina.
+// Quando outra fonte disponível diverge da escolhida, um aviso é registrado.
+func (e *ruleEvaluator) totalMemory(ctx context.Context) (float64, error) {
+	if e.totalMemoryGB > 0 {
+		return e.totalMemoryGB, nil
+	}
+
+	catalog, known, err := e.snapshot.TotalMemoryGB()
+	if err != nil {
+		return 0, fmt.Errorf("error getting total memory: %w", err)
+	}
+	sources := []memorySource{{name: "catalog", value: catalog, estimated: !known}}
+
+	if e.target.MemoryTotalMetric != "" {
+		bytes, ok, err := QueryMetric(ctx, e.source, e.target, e.target.MemoryTotalMetric, config.AggregationMax)
+		if err != nil {
+			return 0, fmt.Errorf("error querying total memory metric: %w", err)
+		}
+		if ok && bytes > 0 {
+			sources = append([]memorySource{{name: "metric", value: bytes / bytesPerGiB}}, sources...)
+		}
+	}
+	if e.target.MemoryTotalGB > 0 {
+		sources = append([]memorySource{{name: "override", value: e.target.MemoryTotalGB}}, sources...)
+	}
+
+	chosen := sources[0]
+	e.warnMemoryMismatch(chosen, sources[1:])
+	e.totalMemoryGB = chosen.value
+	return chosen.value, nil
+}
+
+// memorySource é um valor de memória total e a fonte de onde veio
+type memorySource struct {
+	name  string
+	value float64
+	// estimated indica um formato fora do catálogo, estimado por vCPU
+	estimated bool
+}
+
+// warnMemoryMismatch avisa quando a memória escolhida é uma estimativa ou
+// diverge das demais fontes
+func (e *ruleEvaluator) warnMemoryMismatch(chosen memorySource, others []memorySource) {
+	var mismatch *memorySource
+	for i := range others {
+		if math.Abs(others[i].value-chosen.value)/chosen.value > memoryMismatchTolerance {
+			mismatch = &others[i]
+			break
+		}
+	}
+	if !chosen.estimated && mismatch == nil {
+		return
+	}
+
+	key := fmt.Sprintf("%s=%g", chosen.name, chosen.value)
+	if mismatch != nil {
+		key += fmt.Sprintf(" %s=%g", mismatch.name, mismatch.value)
+	}
+	memoryWarningsMu.Lock()
+	repeated := memoryWarnings[e.target.Name] == key
+	memoryWarnings[e.target.Name] = key
+	memoryWarningsMu.Unlock()
+	if repeated {
+		return
+	}
+
+	event := log.Warn().
+		Str("component", "metrics").
+		Str("action", "memory").
+		Str("target", e.target.Name).
+		Str("source", chosen.name).
+		Float64("totalMemoryGB", math.Round(chosen.value*100)/100)
+	if mismatch == nil {
+		event.Msg("Machine shape not in the catalog, estimating total memory at 8 GB per vCPU; set MEMORY_TOTAL_GB to override")
+		return
+	}
+	event.
+		Str("otherSource", mismatch.name).
+		Float64("otherMemoryGB", math.Round(mismatch.value*100)/100).
+		Msg("Total memory sources disagree, using the preferred source")
+}
+
+// Forget descarta os avisos de memória de um alvo removido da configuração
+func Forget(target string) {
+	memoryWarningsMu.Lock()
+	defer memoryWarningsMu.Unlock()
+	delete(memoryWarnings, target)
+}
@@ -0,0 +1,139 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+const testMemoryMetric = "custom.googleapis.com/memory_total"
+
+func TestTotalMemory(t *testing.T) {
+	tests := []struct {
+		name        string
+		cpus        int
+		override    float64
+		metricGiB   float64
+		metricErr   error
+		want        float64
+		wantWarning string
+		wantErr     bool
+	}{
+		{
+			name: "catalog",
+			cpus: 2,
+			want: 16,
+		},
+		{
+			name:        "shape outside the catalog is estimated",
+			cpus:        3,
+			want:        24,
+			wantWarning: "catalog=24",
+		},
+		{
+			name:      "metric over catalog",
+			cpus:      2,
+			metricGiB: 16.2,
+			want:      16.2,
+		},
+		{
+			name:        "metric disagreeing with the catalog",
+			cpus:        2,
+			metricGiB:   12,
+			want:        12,
+			wantWarning: "metric=12 catalog=16",
+		},
+		{
+			name:      "override over metric and catalog",
+			cpus:      2,
+			override:  16,
+			metricGiB: 16,
+			want:      16,
+		},
+		{
+			name:        "override disagreeing with the metric",
+			cpus:        2,
+			override:    20,
+			metricGiB:   16,
+			want:        20,
+			wantWarning: "override=20 metric=16",
+		},
+		{
+			name:        "override disagreeing with the metric but not the catalog",
+			cpus:        2,
+			override:    16,
+			metricGiB:   32,
+			want:        16,
+			wantWarning: "override=16 metric=32",
+		},
+		{
+			name:      "metric error",
+			cpus:      2,
+			metricErr: errors.New("unavailable"),
+			wantErr:   true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			target := testTarget(t)
+			target.MemoryTotalMetric = testMemoryMetric
+			target.MemoryTotalGB = tt.override
+			source := NewFakeSource()
+			if tt.metricGiB > 0 {
+				source.SetSeries(testMemoryMetric, DoubleSeries(nil, tt.metricGiB*bytesPerGiB))
+			}
+			if tt.metricErr != nil {
+				source.EnqueueError(testMemoryMetric, tt.metricErr)
+			}
+			evaluator := newRuleEvaluator(source, testSnapshot(t, target, 2, tt.cpus, nil), target)
+
+			got, err := evaluator.totalMemory(context.Background())
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("totalMemory() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("totalMemory() = %g, want %g", got, tt.want)
+			}
+			memoryWarningsMu.Lock()
+			warning := memoryWarnings[target.Name]
+			memoryWarningsMu.Unlock()
+			if warning != tt.wantWarning {
+				t.Errorf("memory warning = %q, want %q", warning, tt.wantWarning)
+			}
+		})
+	}
+}
+
+func TestTotalMemoryCached(t *testing.T) {
+	target := testTarget(t)
+	target.MemoryTotalMetric = testMemoryMetric
+	source := NewFakeSource()
+	source.SetSeries(testMemoryMetric, DoubleSeries(nil, 16*bytesPerGiB))
+	evaluator := newRuleEvaluator(source, testSnapshot(t, target, 2, 2, nil), target)
+
+	for range 3 {
+		if _, err := evaluator.totalMemory(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if requests := len(source.Requests()); requests != 1 {
+		t.Errorf("requests = %d, want the total memory queried once per check", requests)
+	}
+}
+
+func TestForgetMemoryWarnings(t *testing.T) {
+	target := testTarget(t)
+	target.MemoryTotalGB = 20
+	evaluator := newRuleEvaluator(NewFakeSource(), testSnapshot(t, target, 2, 2, nil), target)
+	if _, err := evaluator.totalMemory(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	Forget(target.Name)
+	memoryWarningsMu.Lock()
+	_, ok := memoryWarnings[target.Name]
+	memoryWarningsMu.Unlock()
+	if ok {
+		t.Error("memory warning kept after Forget")
+	}
+}
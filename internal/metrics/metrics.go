@@ -6,160 +6,216 @@ import (
 	"math"
 	"time"
 
-	monitoring "cloud.google.com/go/monitoring/apiv3/v2"
-	"cloud.google.com/go/monitoring/apiv3/v2/monitoringpb"
 	"github.com/heraque/alloydb-autoscaler/internal/alloydb"
 	"github.com/heraque/alloydb-autoscaler/internal/config"
 	"github.com/heraque/alloydb-autoscaler/internal/log"
-	"google.golang.org/api/iterator"
-	"google.golang.org/protobuf/types/known/timestamppb"
+	"github.com/heraque/alloydb-autoscaler/internal/telemetry"
+	"github.com/rs/zerolog"
 )
 
-// CheckMetrics checks AlloyDB metrics and updates scaling counters
-func CheckMetrics(ctx context.Context, client *monitoring.MetricClient, currentScaleUpCount, currentScaleDownCount int) (int, int, error) {
-	startTime := time.Now()
+// Result is the outcome of a metrics check
+type Result struct {
+	ScaleUpVotes   int
+	ScaleDownVotes int
+	// CurrentReplicas is the read pool node count observed in the check
+	CurrentReplicas int
+	// DesiredReplicas is the node count computed by the target tracking
+	// policy, 0 with the step policy
+	DesiredReplicas int
+	// Breach is how far the metrics are past their thresholds, as a percentage
+	// of the threshold: the most exceeded metric when voting up, the one
+	// closest to its threshold when voting down, 0 otherwise
+	Breach float64
+}
 
-	memoryFreeBytes, err := QueryMetric(ctx, client, config.Get().MemoryMetric)
-	if err != nil {
-		return 0, 0, fmt.Errorf("error querying free memory: %w", err)
-	}
+// CheckMetrics evaluates the target's rules and updates scaling counters. A
+// cycle votes up when the weights of the rules above their thresholds add up
+// to at least 1, and votes down when every rule that guards scale-down was
+// observed below its threshold. With the target tracking policy it computes
+// the desired node count instead of voting.
+func CheckMetrics(ctx context.Context, source MetricSource, snapshot alloydb.InstanceSnapshot, target config.Target, currentScaleUpCount, currentScaleDownCount int) (Result, error) {
+	startTime := time.Now()
 
-	cpuUsage, err := QueryMetric(ctx, client, config.Get().CPUMetric)
-	if err != nil {
-		return 0, 0, fmt.Errorf("error querying CPU usage: %w", err)
-	}
+	currentCount := snapshot.NodeCount
+	telemetry.SetReadPoolNodes(target.Name, currentCount)
 
-	totalMemoryGB, err := alloydb.GetTotalMemory(ctx)
-	if err != nil {
-		return 0, 0, fmt.Errorf("error getting total memory: %w", err)
+	evaluator := newRuleEvaluator(source, snapshot, target)
+	observations := make([]RuleObservation, 0, len(target.Rules))
+	for _, rule := range target.Rules {
+		obs, err := evaluator.evaluate(ctx, rule)
+		if err != nil {
+			return Result{}, fmt.Errorf("error evaluating rule %s: %w", rule.Name, err)
+		}
+		observations = append(observations, obs)
 	}
 
-	memoryFreeGB := memoryFreeBytes / (1024 * 1024 * 1024)
-	memoryUsedGB := totalMemoryGB - memoryFreeGB
-	memoryUsagePercent := (memoryUsedGB / totalMemoryGB) * 100
-
-	cpuUsagePercent := cpuUsage * 100
-
 	log.Debug().
 		Str("component", "metrics").
 		Str("action", "collect").
-		Str("instance", config.Get().InstanceName).
-		Str("cluster", config.Get().ClusterName).
-		Float64("cpuUsage", math.Round(cpuUsagePercent*100)/100).
-		Str("memoryUsage", fmt.Sprintf("%.2f%%", math.Round(memoryUsagePercent*100)/100)).
-		Str("cpuThreshold", fmt.Sprintf("%.2f%%", config.Get().CPUThreshold)).
-		Str("memoryThreshold", fmt.Sprintf("%.2f%%", config.Get().MemoryThreshold)).
+		Str("instance", target.InstanceName).
+		Str("cluster", target.ClusterName).
+		Dict("rules", ruleValues(observations)).
 		Str("duration", fmt.Sprintf("%.2fs", time.Since(startTime).Seconds())).
 		Msg("AlloyDB resource metrics collected")
 
-	currentCount, err := alloydb.GetReadPoolNodeCount(ctx)
-	if err != nil {
-		return 0, 0, err
+	if target.ScalingPolicy == config.PolicyTargetTracking {
+		return trackTarget(target, observations, currentCount), nil
 	}
 
+	upScore, upBreach, breached := scoreUp(observations)
+	downReady, downBreach := readyForScaleDown(observations)
+
 	newScaleUpCount := currentScaleUpCount
 	newScaleDownCount := currentScaleDownCount
+	var breach float64
 
-	if memoryUsagePercent > config.Get().MemoryThreshold || cpuUsagePercent > config.Get().CPUThreshold {
-		if currentCount < config.Get().MaxReplicas {
+	if upScore >= 1 {
+		if currentCount < target.MaxReplicas {
 			log.Info().
 				Str("component", "scaling").
 				Str("action", "evaluate").
-				Str("instance", config.Get().InstanceName).
-				Float64("cpuUsage", math.Round(cpuUsagePercent*100)/100).
-				Str("memoryUsage", fmt.Sprintf("%.2f%%", math.Round(memoryUsagePercent*100)/100)).
-				Str("cpuThreshold", fmt.Sprintf("%.2f%%", config.Get().CPUThreshold)).
-				Str("memoryThreshold", fmt.Sprintf("%.2f%%", config.Get().MemoryThreshold)).
+				Str("instance", target.InstanceName).
+				Dict("rules", ruleValues(observations)).
+				Strs("breachedRules", breached).
+				Float64("upScore", upScore).
 				Int("currentReplicas", currentCount).
-				Int("maxReplicas", config.Get().MaxReplicas).
+				Int("maxReplicas", target.MaxReplicas).
 				Int("scaleUpVotes", newScaleUpCount+1).
 				Msg("Insufficient resources detected, considering scaling up")
 			newScaleUpCount++
 			newScaleDownCount = 0
+			breach = upBreach
 		} else {
 			log.Warn().
 				Str("component", "scaling").
 				Str("action", "evaluate").
-				Str("instance", config.Get().InstanceName).
+				Str("instance", target.InstanceName).
+				Strs("breachedRules", breached).
 				Int("currentReplicas", currentCount).
-				Int("maxReplicas", config.Get().MaxReplicas).
+				Int("maxReplicas", target.MaxReplicas).
 				Msg("Insufficient resources detected, but maximum replicas limit reached")
 		}
-	} else if currentCount > config.Get().MinReplicas {
+	} else if upScore == 0 && downReady && currentCount > target.MinReplicas {
 		log.Info().
 			Str("component", "scaling").
 			Str("action", "evaluate").
-			Str("instance", config.Get().InstanceName).
-			Float64("cpuUsage", math.Round(cpuUsagePercent*100)/100).
-			Str("memoryUsage", fmt.Sprintf("%.2f%%", math.Round(memoryUsagePercent*100)/100)).
-			Str("cpuThreshold", fmt.Sprintf("%.2f%%", config.Get().CPUThreshold)).
-			Str("memoryThreshold", fmt.Sprintf("%.2f%%", config.Get().MemoryThreshold)).
+			Str("instance", target.InstanceName).
+			Dict("rules", ruleValues(observations)).
 			Int("currentReplicas", currentCount).
-			Int("minReplicas", config.Get().MinReplicas).
+			Int("minReplicas", target.MinReplicas).
 			Int("scaleDownVotes", newScaleDownCount+1).
 			Msg("Excess resources detected, considering scaling down")
 		newScaleDownCount++
 		newScaleUpCount = 0
+		breach = downBreach
 	} else {
-		LogNormalResources(currentCount)
+		LogNormalResources(target, currentCount)
 		newScaleUpCount = 0
 		newScaleDownCount = 0
 	}
 
-	return newScaleUpCount, newScaleDownCount, nil
+	return Result{
+		ScaleUpVotes:    newScaleUpCount,
+		ScaleDownVotes:  newScaleDownCount,
+		CurrentReplicas: currentCount,
+		Breach:          breach,
+	}, nil
 }
 
-// QueryMetric queries a specific metric from Cloud Monitoring
-func QueryMetric(ctx context.Context, client *monitoring.MetricClient, metricType string) (float64, error) {
-	now := time.Now()
-	startTime := now.Add(-5 * time.Minute)
-
-	req := &monitoringpb.ListTimeSeriesRequest{
-		Name:   fmt.Sprintf("projects/%s", config.Get().GCPProject),
-		Filter: fmt.Sprintf(`metric.type = "%s" AND resource.labels.instance_id = "%s"`, metricType, config.Get().InstanceName),
-		Interval: &monitoringpb.TimeInterval{
-			StartTime: timestamppb.New(startTime),
-			EndTime:   timestamppb.New(now),
-		},
-		View: monitoringpb.ListTimeSeriesRequest_FULL,
+// scoreUp soma os pesos das regras acima do limite e retorna a maior
+// ultrapassagem entre elas
+func scoreUp(observations []RuleObservation) (score, breach float64, breached []string) {
+	for _, obs := range observations {
+		if !obs.Observed || !obs.Rule.VotesUp() || obs.Value <= obs.Rule.Threshold {
+			continue
+		}
+		score += obs.Rule.Weight
+		breach = max(breach, breachPercent(obs.Value, obs.Rule.Threshold))
+		breached = append(breached, obs.Rule.Name)
 	}
+	return score, breach, breached
+}
 
-	it := client.ListTimeSeries(ctx, req)
-	var lastValue float64
-	for {
-		resp, err := it.Next()
-		if err == iterator.Done {
-			break
+// readyForScaleDown exige que todas as regras que protegem a redução tenham
+// sido observadas abaixo do limite para reduzir; uma regra sem dados impede a
+// redução. breach é a menor folga entre elas.
+func readyForScaleDown(observations []RuleObservation) (bool, float64) {
+	guarded := false
+	breach := math.Inf(1)
+	for _, obs := range observations {
+		if !obs.Rule.GuardsDown() {
+			continue
 		}
-		if err != nil {
-			if ctx.Err() == context.DeadlineExceeded {
-				return 0, fmt.Errorf("timeout querying metric %s: %w", metricType, err)
-			}
-			return 0, fmt.Errorf("error iterating time series: %w", err)
+		if !obs.Observed || obs.Value > obs.Rule.ScaleDownThreshold {
+			return false, 0
 		}
-		if len(resp.Points) > 0 {
-			switch v := resp.Points[0].Value.Value.(type) {
-			case *monitoringpb.TypedValue_DoubleValue:
-				lastValue = v.DoubleValue
-			case *monitoringpb.TypedValue_Int64Value:
-				lastValue = float64(v.Int64Value)
-			default:
-				return 0, fmt.Errorf("unsupported value type: %T", v)
-			}
+		guarded = true
+		breach = min(breach, -breachPercent(obs.Value, obs.Rule.ScaleDownThreshold))
+	}
+	if !guarded {
+		return false, 0
+	}
+	return true, breach
+}
+
+// ruleValues monta um objeto de log com o valor observado de cada regra
+func ruleValues(observations []RuleObservation) *zerolog.Event {
+	dict := zerolog.Dict()
+	for _, obs := range observations {
+		if obs.Observed {
+			dict.Float64(obs.Rule.Name, math.Round(obs.Value*100)/100)
+		} else {
+			dict.Str(obs.Rule.Name, "no data")
 		}
 	}
+	return dict
+}
+
+// breachPercent returns how far value is above threshold, as a percentage of
+// the threshold; negative when below it
+func breachPercent(value, threshold float64) float64 {
+	if threshold == 0 {
+		return 0
+	}
+	return (value - threshold) / threshold * 100
+}
+
+// QueryMetric returns the latest value of metricType for the target's read
+// pool, aligned on the server and combined across nodes with aggregation
+// (config.AggregationMax, AggregationMean or AggregationP95). The second
+// result is false when the metric has no data in the queried window.
+func QueryMetric(ctx context.Context, source MetricSource, target config.Target, metricType, aggregation string) (float64, bool, error) {
+	nodes, err := QueryNodeValues(ctx, source, target, config.Rule{Metric: metricType})
+	if err != nil || len(nodes) == 0 {
+		return 0, false, err
+	}
+
+	values := make([]float64, len(nodes))
+	for i, nv := range nodes {
+		values[i] = nv.Value
+	}
+	value := aggregateNodes(values, aggregation)
 
-	return lastValue, nil
+	log.Debug().
+		Str("component", "metrics").
+		Str("action", "collect").
+		Str("target", target.Name).
+		Str("metric", metricType).
+		Str("nodeAggregation", aggregation).
+		Dict("nodes", nodeValues(nodes)).
+		Float64("value", math.Round(value*100)/100).
+		Msg("Metric queried")
+	return value, true, nil
 }
 
 // LogNormalResources logs when resources are within normal thresholds
-func LogNormalResources(count int) {
+func LogNormalResources(target config.Target, count int) {
 	log.Info().
 		Str("component", "scaling").
 		Str("action", "evaluate").
-		Str("instance", config.Get().InstanceName).
+		Str("instance", target.InstanceName).
 		Int("currentReplicas", count).
-		Int("minReplicas", config.Get().MinReplicas).
-		Int("maxReplicas", config.Get().MaxReplicas).
+		Int("minReplicas", target.MinReplicas).
+		Int("maxReplicas", target.MaxReplicas).
 		Msg("AlloyDB resources within normal thresholds")
 }
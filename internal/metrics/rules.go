@@ -0,0 +1,218 @@
+package metrics
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+
+	"cloud.google.com/go/monitoring/apiv3/v2/monitoringpb"
+	"github.com/heraque/alloydb-autoscaler/internal/alloydb"
+	"github.com/heraque/alloydb-autoscaler/internal/config"
+	"github.com/heraque/alloydb-autoscaler/internal/log"
+	"github.com/heraque/alloydb-autoscaler/internal/telemetry"
+	"google.golang.org/protobuf/types/known/durationpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// bytesPerGiB converte bytes reportados pelo Cloud Monitoring em GiB
+const bytesPerGiB = 1024 * 1024 * 1024
+
+// alignmentPeriod é o período de alinhamento das regras com aligner
+var alignmentPeriod = durationpb.New(time.Minute)
+
+// RuleObservation is the value of a rule observed in one check
+type RuleObservation struct {
+	Rule  config.Rule
+	Value float64
+	// Observed is false when the metric had no data or could not be converted
+	Observed bool
+}
+
+// ruleEvaluator consulta e converte as regras de um alvo em uma verificação,
+// usando o snapshot da instância lido no início do ciclo
+type ruleEvaluator struct {
+	source   MetricSource
+	snapshot alloydb.InstanceSnapshot
+	target   config.Target
+
+	totalMemoryGB  float64
+	maxConnections *int
+}
+
+func newRuleEvaluator(source MetricSource, snapshot alloydb.InstanceSnapshot, target config.Target) *ruleEvaluator {
+	return &ruleEvaluator{source: source, snapshot: snapshot, target: target}
+}
+
+// evaluate consulta a métrica da regra e combina as séries retornadas, já
+// convertidas, pela agregação entre nós da regra. A combinação ocorre depois
+// da conversão porque conversões como used_memory_percent invertem a ordem
+// dos valores: o nó com mais memória livre é o menos carregado.
+func (e *ruleEvaluator) evaluate(ctx context.Context, rule config.Rule) (RuleObservation, error) {
+	obs := RuleObservation{Rule: rule}
+
+	values, err := QueryNodeValues(ctx, e.source, e.target, rule)
+	if err != nil {
+		return obs, err
+	}
+	if len(values) == 0 {
+		log.Debug().
+			Str("component", "metrics").
+			Str("action", "collect").
+			Str("target", e.target.Name).
+			Str("rule", rule.Name).
+			Str("metric", rule.Metric).
+			Msg("No data for rule")
+		return obs, nil
+	}
+
+	// Uma única série sem node_id é um valor da instância inteira
+	instanceLevel := len(values) == 1 && values[0].Node == ""
+	converted := make([]NodeValue, len(values))
+	nodeResults := make([]float64, len(values))
+	for i, nv := range values {
+		value, ok, err := e.convert(ctx, rule, nv.Value, instanceLevel)
+		if err != nil || !ok {
+			return obs, err
+		}
+		converted[i] = NodeValue{Node: nv.Node, Value: value * rule.Scale}
+		nodeResults[i] = converted[i].Value
+	}
+	obs.Value = aggregateNodes(nodeResults, rule.NodeAggregation)
+	obs.Observed = true
+
+	log.Debug().
+		Str("component", "metrics").
+		Str("action", "collect").
+		Str("target", e.target.Name).
+		Str("rule", rule.Name).
+		Str("metric", rule.Metric).
+		Float64("value", math.Round(obs.Value*100)/100).
+		Float64("threshold", rule.Threshold).
+		Float64("scaleDownThreshold", rule.ScaleDownThreshold).
+		Str("direction", rule.Direction).
+		Float64("weight", rule.Weight).
+		Str("nodeAggregation", rule.NodeAggregation).
+		Dict("nodes", nodeValues(converted)).
+		Msg("Rule evaluated")
+	telemetry.SetRuleValue(e.target.Name, rule.Name, obs.Value)
+	return obs, nil
+}
+
+// convert aplica a conversão de unidade da regra ao valor de uma série
+func (e *ruleEvaluator) convert(ctx context.Context, rule config.Rule, raw float64, instanceLevel bool) (float64, bool, error) {
+	switch rule.Conversion {
+	case config.ConversionPercent:
+		return raw * 100, true, nil
+
+	case config.ConversionBytesToGiB:
+		return raw / bytesPerGiB, true, nil
+
+	case config.ConversionUsedMemoryPercent:
+		totalMemoryGB, err := e.totalMemory(ctx)
+		if err != nil {
+			return 0, false, err
+		}
+		freeGB := raw / bytesPerGiB
+		return (totalMemoryGB - freeGB) / totalMemoryGB * 100, true, nil
+
+	case config.ConversionConnectionPercent:
+		maxConnections, err := e.maxConnectionsLimit(ctx)
+		if err != nil || maxConnections == 0 {
+			return 0, false, err
+		}
+		// Sem reducer, o valor da instância é o total de conexões, dividido
+		// igualmente entre os nós; cada nó tem seu próprio max_connections
+		if instanceLevel && rule.Reducer == "" && e.snapshot.NodeCount > 0 {
+			raw /= float64(e.snapshot.NodeCount)
+		}
+		return raw / float64(maxConnections) * 100, true, nil
+
+	default:
+		return raw, true, nil
+	}
+}
+
+// maxConnectionsLimit usa MAX_CONNECTIONS do alvo ou a flag da instância.
+// Retorna 0 quando nenhum dos dois está disponível.
+func (e *ruleEvaluator) maxConnectionsLimit(ctx context.Context) (int, error) {
+	if e.maxConnections != nil {
+		return *e.maxConnections, nil
+	}
+
+	limit := e.target.MaxConnections
+	if limit == 0 {
+		var err error
+		limit, err = e.snapshot.MaxConnections()
+		if err != nil {
+			return 0, err
+		}
+	}
+	if limit == 0 {
+		log.Warn().
+			Str("component", "metrics").
+			Str("action", "collect").
+			Str("target", e.target.Name).
+			Msg("max_connections flag is not set on the instance and MAX_CONNECTIONS is not configured, skipping connection rules")
+	}
+	e.maxConnections = &limit
+	return limit, nil
+}
+
+// NodeValue is the latest value of one time series. Node is the node_id label,
+// empty for instance-level series.
+type NodeValue struct {
+	Node  string
+	Value float64
+}
+
+// QueryNodeValues returns the latest aligned value of each series of the
+// rule's metric. Each series is aligned on the server with the rule's aligner
+// (ALIGN_MEAN by default), so the value covers a whole alignment period rather
+// than a single sample; the rule's reducer, when set, combines series on the
+// server as well.
+func QueryNodeValues(ctx context.Context, source MetricSource, target config.Target, rule config.Rule) ([]NodeValue, error) {
+	now := time.Now()
+	aligner := rule.Aligner
+	if aligner == "" {
+		aligner = "ALIGN_MEAN"
+	}
+	req := &monitoringpb.ListTimeSeriesRequest{
+		Name:   fmt.Sprintf("projects/%s", target.GCPProject),
+		Filter: seriesFilter(target, rule.Metric),
+		Interval: &monitoringpb.TimeInterval{
+			StartTime: timestamppb.New(now.Add(-5 * time.Minute)),
+			EndTime:   timestamppb.New(now),
+		},
+		Aggregation: &monitoringpb.Aggregation{
+			AlignmentPeriod:  alignmentPeriod,
+			PerSeriesAligner: monitoringpb.Aggregation_Aligner(monitoringpb.Aggregation_Aligner_value[aligner]),
+		},
+		View: monitoringpb.ListTimeSeriesRequest_FULL,
+	}
+	if rule.Reducer != "" {
+		req.Aggregation.CrossSeriesReducer = monitoringpb.Aggregation_Reducer(monitoringpb.Aggregation_Reducer_value[rule.Reducer])
+	}
+
+	series, err := source.ListTimeSeries(ctx, req)
+	if err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			return nil, fmt.Errorf("timeout querying metric %s: %w", rule.Metric, err)
+		}
+		return nil, err
+	}
+
+	values := make([]NodeValue, 0, len(series))
+	for _, ts := range series {
+		if len(ts.Points) == 0 {
+			continue
+		}
+		// Os pontos vêm do mais recente para o mais antigo
+		value, err := pointValue(ts.Points[0])
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, NodeValue{Node: seriesNode(ts), Value: value})
+	}
+	return values, nil
+}
@@ -0,0 +1,396 @@
+package metrics
+
+import (
+	"context"
+	"math"
+	"testing"
+
+	"cloud.google.com/go/monitoring/apiv3/v2/monitoringpb"
+	"github.com/heraque/alloydb-autoscaler/internal/alloydb"
+	"github.com/heraque/alloydb-autoscaler/internal/config"
+)
+
+const testMetric = "custom.googleapis.com/load"
+
+// testTarget devolve um alvo com o nome do teste, que identifica os avisos
+// de memória e as métricas dele
+func testTarget(t *testing.T, rules ...config.Rule) config.Target {
+	t.Cleanup(func() { Forget(t.Name()) })
+	return config.Target{
+		Name:          t.Name(),
+		GCPProject:    "project",
+		Region:        "us-central1",
+		ClusterName:   "cluster",
+		InstanceName:  "read-pool",
+		MinReplicas:   1,
+		MaxReplicas:   5,
+		ScalingPolicy: config.PolicyStep,
+		Rules:         rules,
+	}
+}
+
+// testSnapshot lê de um Fake o snapshot de uma instância com nodes nós de
+// cpus vCPUs e as flags de banco informadas
+func testSnapshot(t *testing.T, target config.Target, nodes, cpus int, flags map[string]string) alloydb.InstanceSnapshot {
+	t.Helper()
+	api := alloydb.NewFake()
+	if err := api.AddInstance(target.InstancePath(), nodes, cpus); err != nil {
+		t.Fatal(err)
+	}
+	instance, err := api.Instance(target.InstancePath())
+	if err != nil {
+		t.Fatal(err)
+	}
+	instance.DatabaseFlags = flags
+	if err := api.SetInstance(instance); err != nil {
+		t.Fatal(err)
+	}
+	snapshot, err := alloydb.GetSnapshot(context.Background(), api, target)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return snapshot
+}
+
+// nodeSeries devolve uma série double de um nó do read pool
+func nodeSeries(node string, value float64) *monitoringpb.TimeSeries {
+	return DoubleSeries(map[string]string{"node_id": node}, value)
+}
+
+// rule devolve uma regra sobre testMetric sem conversão
+func rule(direction string, threshold, scaleDown, weight float64) config.Rule {
+	return config.Rule{
+		Name:               "load",
+		Metric:             testMetric,
+		Conversion:         config.ConversionNone,
+		NodeAggregation:    config.AggregationMax,
+		Scale:              1,
+		Threshold:          threshold,
+		ScaleDownThreshold: scaleDown,
+		Direction:          direction,
+		Weight:             weight,
+	}
+}
+
+// observe devolve a observação de uma regra com limite de aumento threshold
+// e de redução scaleDown
+func observe(name, direction string, threshold, scaleDown, value float64) RuleObservation {
+	return RuleObservation{
+		Rule: config.Rule{
+			Name:               name,
+			Threshold:          threshold,
+			ScaleDownThreshold: scaleDown,
+			Direction:          direction,
+			Weight:             1,
+		},
+		Value:    value,
+		Observed: true,
+	}
+}
+
+func TestRuleEvaluate(t *testing.T) {
+	connections := func(reducer string) config.Rule {
+		r := rule(config.DirectionBoth, 80, 80, 1)
+		r.Conversion = config.ConversionConnectionPercent
+		r.Reducer = reducer
+		return r
+	}
+	withRule := func(modify func(*config.Rule)) config.Rule {
+		r := rule(config.DirectionBoth, 80, 80, 1)
+		modify(&r)
+		return r
+	}
+	maxConnections := map[string]string{"max_connections": "200"}
+
+	tests := []struct {
+		name           string
+		rule           config.Rule
+		series         []*monitoringpb.TimeSeries
+		flags          map[string]string
+		maxConnections int
+		want           float64
+		wantObserved   bool
+	}{
+		{
+			name:         "max of the nodes",
+			rule:         rule(config.DirectionBoth, 80, 80, 1),
+			series:       []*monitoringpb.TimeSeries{nodeSeries("a", 40), nodeSeries("b", 90)},
+			want:         90,
+			wantObserved: true,
+		},
+		{
+			name:         "mean of the nodes",
+			rule:         withRule(func(r *config.Rule) { r.NodeAggregation = config.AggregationMean }),
+			series:       []*monitoringpb.TimeSeries{nodeSeries("a", 40), nodeSeries("b", 90)},
+			want:         65,
+			wantObserved: true,
+		},
+		{
+			name:         "percent with scale",
+			rule:         withRule(func(r *config.Rule) { r.Conversion = config.ConversionPercent; r.Scale = 2 }),
+			series:       []*monitoringpb.TimeSeries{nodeSeries("a", 0.3)},
+			want:         60,
+			wantObserved: true,
+		},
+		{
+			name:         "bytes to GiB",
+			rule:         withRule(func(r *config.Rule) { r.Conversion = config.ConversionBytesToGiB }),
+			series:       []*monitoringpb.TimeSeries{nodeSeries("a", 3*bytesPerGiB)},
+			want:         3,
+			wantObserved: true,
+		},
+		{
+			name: "used memory percent of the catalog memory",
+			rule: withRule(func(r *config.Rule) { r.Conversion = config.ConversionUsedMemoryPercent }),
+			// 2 vCPUs têm 16 GB; 4 GiB livres são 75% de uso
+			series:       []*monitoringpb.TimeSeries{nodeSeries("a", 4*bytesPerGiB)},
+			want:         75,
+			wantObserved: true,
+		},
+		{
+			name:   "no data",
+			rule:   rule(config.DirectionBoth, 80, 80, 1),
+			series: nil,
+		},
+		{
+			name: "connection percent of the instance total without a reducer",
+			rule: connections(""),
+			// 200 conexões divididas entre 2 nós, de 200 por nó
+			series:       []*monitoringpb.TimeSeries{DoubleSeries(nil, 200)},
+			flags:        maxConnections,
+			want:         50,
+			wantObserved: true,
+		},
+		{
+			name:         "connection percent with a reducer",
+			rule:         connections("REDUCE_MAX"),
+			series:       []*monitoringpb.TimeSeries{DoubleSeries(nil, 150)},
+			flags:        maxConnections,
+			want:         75,
+			wantObserved: true,
+		},
+		{
+			name:         "connection percent per node",
+			rule:         connections(""),
+			series:       []*monitoringpb.TimeSeries{nodeSeries("a", 100), nodeSeries("b", 160)},
+			flags:        maxConnections,
+			want:         80,
+			wantObserved: true,
+		},
+		{
+			name:           "MAX_CONNECTIONS overrides the flag",
+			rule:           connections(""),
+			series:         []*monitoringpb.TimeSeries{nodeSeries("a", 100)},
+			flags:          maxConnections,
+			maxConnections: 400,
+			want:           25,
+			wantObserved:   true,
+		},
+		{
+			name:   "connection percent without max_connections",
+			rule:   connections(""),
+			series: []*monitoringpb.TimeSeries{nodeSeries("a", 100)},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			target := testTarget(t, tt.rule)
+			target.MaxConnections = tt.maxConnections
+			source := NewFakeSource()
+			source.SetSeries(testMetric, tt.series...)
+			evaluator := newRuleEvaluator(source, testSnapshot(t, target, 2, 2, tt.flags), target)
+
+			got, err := evaluator.evaluate(context.Background(), tt.rule)
+			if err != nil {
+				t.Fatalf("evaluate() error = %v", err)
+			}
+			if got.Observed != tt.wantObserved || math.Abs(got.Value-tt.want) > 1e-9 {
+				t.Errorf("evaluate() = %g (observed %v), want %g (observed %v)", got.Value, got.Observed, tt.want, tt.wantObserved)
+			}
+		})
+	}
+}
+
+func TestRuleEvaluateReducer(t *testing.T) {
+	r := rule(config.DirectionBoth, 80, 80, 1)
+	r.Reducer = "REDUCE_SUM"
+	target := testTarget(t, r)
+	source := NewFakeSource()
+	evaluator := newRuleEvaluator(source, testSnapshot(t, target, 2, 2, nil), target)
+
+	if _, err := evaluator.evaluate(context.Background(), r); err != nil {
+		t.Fatal(err)
+	}
+	requests := source.Requests()
+	if len(requests) != 1 {
+		t.Fatalf("requests = %d, want 1", len(requests))
+	}
+	aggregation := requests[0].GetAggregation()
+	if aggregation.GetCrossSeriesReducer() != monitoringpb.Aggregation_REDUCE_SUM || aggregation.GetPerSeriesAligner() != monitoringpb.Aggregation_ALIGN_MEAN {
+		t.Errorf("aggregation = %v, want REDUCE_SUM over ALIGN_MEAN", aggregation)
+	}
+}
+
+func TestCheckMetricsVotes(t *testing.T) {
+	cpu := rule(config.DirectionBoth, 70, 30, 1)
+	cpu.Name, cpu.Metric = "cpu", "custom.googleapis.com/cpu"
+	queue := rule(config.DirectionUp, 100, 100, 1)
+	queue.Name, queue.Metric = "queue", "custom.googleapis.com/queue"
+	memory := rule(config.DirectionDown, 80, 50, 1)
+	memory.Name, memory.Metric = "memory", "custom.googleapis.com/memory"
+	half := func(r config.Rule) config.Rule {
+		r.Weight = 0.5
+		return r
+	}
+
+	tests := []struct {
+		name       string
+		rules      []config.Rule
+		values     map[string]float64
+		nodes      int
+		up, down   int
+		wantUp     int
+		wantDown   int
+		wantBreach float64
+	}{
+		{
+			name:       "above the threshold votes up",
+			rules:      []config.Rule{cpu},
+			values:     map[string]float64{"cpu": 91},
+			wantUp:     1,
+			wantBreach: 30,
+		},
+		{
+			name:       "votes accumulate",
+			rules:      []config.Rule{cpu},
+			values:     map[string]float64{"cpu": 91},
+			up:         2,
+			wantUp:     3,
+			wantBreach: 30,
+		},
+		{
+			name:       "voting up resets the scale down votes",
+			rules:      []config.Rule{cpu},
+			values:     map[string]float64{"cpu": 91},
+			down:       2,
+			wantUp:     1,
+			wantBreach: 30,
+		},
+		{
+			name:   "one rule with half weight does not vote",
+			rules:  []config.Rule{half(cpu), half(queue)},
+			values: map[string]float64{"cpu": 91, "queue": 50},
+			up:     2,
+		},
+		{
+			name:       "two rules with half weight vote up",
+			rules:      []config.Rule{half(cpu), half(queue)},
+			values:     map[string]float64{"cpu": 91, "queue": 150},
+			wantUp:     1,
+			wantBreach: 50,
+		},
+		{
+			name:   "at max replicas keeps the votes",
+			rules:  []config.Rule{cpu},
+			values: map[string]float64{"cpu": 91},
+			nodes:  5,
+			up:     2,
+			wantUp: 2,
+		},
+		{
+			name:       "below the scale down threshold votes down",
+			rules:      []config.Rule{cpu},
+			values:     map[string]float64{"cpu": 15},
+			up:         1,
+			wantDown:   1,
+			wantBreach: 50,
+		},
+		{
+			name:   "inside the hysteresis band does not vote",
+			rules:  []config.Rule{cpu},
+			values: map[string]float64{"cpu": 50},
+			down:   2,
+		},
+		{
+			name:   "at min replicas does not vote down",
+			rules:  []config.Rule{cpu},
+			values: map[string]float64{"cpu": 15},
+			nodes:  1,
+			down:   2,
+		},
+		{
+			name:       "up-only rule does not guard scale down",
+			rules:      []config.Rule{cpu, queue},
+			values:     map[string]float64{"cpu": 15, "queue": 90},
+			wantDown:   1,
+			wantBreach: 50,
+		},
+		{
+			name:   "down-only rule above its threshold blocks scale down without voting up",
+			rules:  []config.Rule{cpu, memory},
+			values: map[string]float64{"cpu": 15, "memory": 95},
+		},
+		{
+			name:       "scale down breach is the smallest margin",
+			rules:      []config.Rule{cpu, memory},
+			values:     map[string]float64{"cpu": 15, "memory": 40},
+			wantDown:   1,
+			wantBreach: 20,
+		},
+		{
+			name:   "rule without data blocks scale down",
+			rules:  []config.Rule{cpu, memory},
+			values: map[string]float64{"cpu": 15},
+			down:   2,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			target := testTarget(t, tt.rules...)
+			source := NewFakeSource()
+			for _, r := range tt.rules {
+				if value, ok := tt.values[r.Name]; ok {
+					source.SetSeries(r.Metric, nodeSeries("a", value))
+				}
+			}
+			nodes := tt.nodes
+			if nodes == 0 {
+				nodes = 3
+			}
+
+			got, err := CheckMetrics(context.Background(), source, testSnapshot(t, target, nodes, 2, nil), target, tt.up, tt.down)
+			if err != nil {
+				t.Fatalf("CheckMetrics() error = %v", err)
+			}
+			if got.ScaleUpVotes != tt.wantUp || got.ScaleDownVotes != tt.wantDown || math.Abs(got.Breach-tt.wantBreach) > 1e-9 {
+				t.Errorf("CheckMetrics() = up %d, down %d, breach %g; want up %d, down %d, breach %g",
+					got.ScaleUpVotes, got.ScaleDownVotes, got.Breach, tt.wantUp, tt.wantDown, tt.wantBreach)
+			}
+		})
+	}
+}
+
+func TestReadyForScaleDown(t *testing.T) {
+	unobserved := observe("memory", config.DirectionBoth, 80, 50, 0)
+	unobserved.Observed = false
+
+	tests := []struct {
+		name         string
+		observations []RuleObservation
+		want         bool
+	}{
+		{name: "below the scale down threshold", observations: []RuleObservation{observe("cpu", config.DirectionBoth, 70, 30, 20)}, want: true},
+		{name: "at the scale down threshold", observations: []RuleObservation{observe("cpu", config.DirectionBoth, 70, 30, 30)}, want: true},
+		{name: "between both thresholds", observations: []RuleObservation{observe("cpu", config.DirectionBoth, 70, 30, 31)}},
+		{name: "up-only rules only", observations: []RuleObservation{observe("queue", config.DirectionUp, 100, 100, 0)}},
+		{name: "unobserved rule", observations: []RuleObservation{observe("cpu", config.DirectionBoth, 70, 30, 20), unobserved}},
+		{name: "no rules"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got, _ := readyForScaleDown(tt.observations); got != tt.want {
+				t.Errorf("readyForScaleDown() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,97 @@
+package metrics
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	monitoring "cloud.google.com/go/monitoring/apiv3/v2"
+	"cloud.google.com/go/monitoring/apiv3/v2/monitoringpb"
+	"github.com/heraque/alloydb-autoscaler/internal/resilience"
+	"github.com/heraque/alloydb-autoscaler/internal/telemetry"
+	"google.golang.org/api/iterator"
+)
+
+// MetricSource abstrai a origem das séries temporais usadas nas decisões de escala
+type MetricSource interface {
+	ListTimeSeries(ctx context.Context, req *monitoringpb.ListTimeSeriesRequest) ([]*monitoringpb.TimeSeries, error)
+}
+
+// monitoringSource implementa MetricSource sobre o Cloud Monitoring
+type monitoringSource struct {
+	client *monitoring.MetricClient
+}
+
+// NewMonitoringSource cria uma MetricSource baseada no cliente do Cloud Monitoring
+func NewMonitoringSource(client *monitoring.MetricClient) MetricSource {
+	return &monitoringSource{client: client}
+}
+
+func (s *monitoringSource) ListTimeSeries(ctx context.Context, req *monitoringpb.ListTimeSeriesRequest) ([]*monitoringpb.TimeSeries, error) {
+	it := s.client.ListTimeSeries(ctx, req)
+	var series []*monitoringpb.TimeSeries
+	for {
+		resp, err := it.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("error iterating time series: %w", err)
+		}
+		series = append(series, resp)
+	}
+	return series, nil
+}
+
+// instrumentedSource registra latência e erros das consultas à MetricSource
+type instrumentedSource struct {
+	next MetricSource
+}
+
+// InstrumentSource envolve a MetricSource para expor métricas de latência e erros
+func InstrumentSource(source MetricSource) MetricSource {
+	return &instrumentedSource{next: source}
+}
+
+func (s *instrumentedSource) ListTimeSeries(ctx context.Context, req *monitoringpb.ListTimeSeriesRequest) ([]*monitoringpb.TimeSeries, error) {
+	start := time.Now()
+	series, err := s.next.ListTimeSeries(ctx, req)
+	telemetry.ObserveAPICall("monitoring", "ListTimeSeries", start, err)
+	return series, err
+}
+
+// pointValue converte o valor tipado de um ponto para float64
+func pointValue(point *monitoringpb.Point) (float64, error) {
+	switch v := point.GetValue().GetValue().(type) {
+	case *monitoringpb.TypedValue_DoubleValue:
+		return v.DoubleValue, nil
+	case *monitoringpb.TypedValue_Int64Value:
+		return float64(v.Int64Value), nil
+	case *monitoringpb.TypedValue_BoolValue:
+		if v.BoolValue {
+			return 1, nil
+		}
+		return 0, nil
+	case *monitoringpb.TypedValue_DistributionValue:
+		return v.DistributionValue.GetMean(), nil
+	default:
+		return 0, fmt.Errorf("unsupported value type: %T", v)
+	}
+}
+
+// resilientSource repete consultas que falham por erro transitório ou de cota
+// e as passa pelo circuit breaker da API do Cloud Monitoring
+type resilientSource struct {
+	next MetricSource
+}
+
+// ResilientSource envolve a MetricSource com novas tentativas e circuit breaker
+func ResilientSource(source MetricSource) MetricSource {
+	return &resilientSource{next: source}
+}
+
+func (s *resilientSource) ListTimeSeries(ctx context.Context, req *monitoringpb.ListTimeSeriesRequest) ([]*monitoringpb.TimeSeries, error) {
+	return resilience.Call(ctx, "monitoring", "ListTimeSeries", true, func(ctx context.Context) ([]*monitoringpb.TimeSeries, error) {
+		return s.next.ListTimeSeries(ctx, req)
+	})
+}
@@ -0,0 +1,79 @@
+package metrics
+
+import (
+	"math"
+
+	"github.com/heraque/alloydb-autoscaler/internal/config"
+	"github.com/heraque/alloydb-autoscaler/internal/log"
+)
+
+// desiredReplicas calcula, como o HPA do Kubernetes, o número de nós que leva
+// cada regra ao seu limite: ceil(atual × valor/limite). Desvios dentro da
+// tolerância mantêm o número atual. Uma regra que só vota para aumentar não
+// impede a redução, e uma que só protege a redução não força aumento. Reduzir
+// exige, como no modo step, todas as regras de proteção abaixo do limite de
+// redução. O resultado é ajustado a MinReplicas/MaxReplicas.
+func desiredReplicas(target config.Target, observations []RuleObservation, current int) (int, string) {
+	if current == 0 {
+		return current, ""
+	}
+	tolerance := target.TargetTrackingTolerance / 100
+
+	desired, driver := 0, ""
+	for _, obs := range observations {
+		if !obs.Observed || obs.Rule.Threshold <= 0 {
+			continue
+		}
+		n := current
+		if ratio := obs.Value / obs.Rule.Threshold; math.Abs(ratio-1) > tolerance {
+			n = int(math.Ceil(float64(current) * ratio))
+		}
+
+		switch {
+		case n > current && obs.Rule.VotesUp():
+		case n <= current && obs.Rule.GuardsDown():
+		case obs.Rule.GuardsDown():
+			// Acima do limite, mas a regra não vota para aumentar
+			n = current
+		default:
+			continue
+		}
+		if n > desired {
+			desired, driver = n, obs.Rule.Name
+		}
+	}
+
+	if desired == 0 {
+		desired = current
+	}
+	if desired < current {
+		if ready, _ := readyForScaleDown(observations); !ready {
+			desired = current
+		}
+	}
+	return min(max(desired, target.MinReplicas), target.MaxReplicas), driver
+}
+
+// trackTarget produz o resultado de uma verificação no modo target tracking:
+// sem votos, apenas o número de nós desejado
+func trackTarget(target config.Target, observations []RuleObservation, current int) Result {
+	desired, driver := desiredReplicas(target, observations, current)
+
+	if desired == current {
+		LogNormalResources(target, current)
+	} else {
+		log.Info().
+			Str("component", "scaling").
+			Str("action", "evaluate").
+			Str("instance", target.InstanceName).
+			Dict("rules", ruleValues(observations)).
+			Str("rule", driver).
+			Int("currentReplicas", current).
+			Int("desiredReplicas", desired).
+			Int("minReplicas", target.MinReplicas).
+			Int("maxReplicas", target.MaxReplicas).
+			Msg("Desired replica count differs from current, considering scaling")
+	}
+
+	return Result{CurrentReplicas: current, DesiredReplicas: desired}
+}
@@ -0,0 +1,140 @@
+package metrics
+
+import (
+	"testing"
+
+	"github.com/heraque/alloydb-autoscaler/internal/config"
+)
+
+func TestDesiredReplicas(t *testing.T) {
+	unobserved := observe("memory", config.DirectionBoth, 80, 80, 0)
+	unobserved.Observed = false
+	weightless := observe("connections", config.DirectionBoth, 50, 50, 500)
+	weightless.Rule.Weight = 0
+
+	tests := []struct {
+		name         string
+		current      int
+		min, max     int
+		observations []RuleObservation
+		want         int
+		wantDriver   string
+	}{
+		{
+			name:         "scale up to the threshold",
+			current:      4,
+			observations: []RuleObservation{observe("cpu", config.DirectionBoth, 60, 60, 90)},
+			want:         6,
+			wantDriver:   "cpu",
+		},
+		{
+			name:         "within tolerance",
+			current:      4,
+			observations: []RuleObservation{observe("cpu", config.DirectionBoth, 60, 60, 64)},
+			want:         4,
+			wantDriver:   "cpu",
+		},
+		{
+			name:         "just outside tolerance",
+			current:      4,
+			observations: []RuleObservation{observe("cpu", config.DirectionBoth, 60, 60, 67)},
+			want:         5,
+			wantDriver:   "cpu",
+		},
+		{
+			name:         "scale down",
+			current:      4,
+			observations: []RuleObservation{observe("cpu", config.DirectionBoth, 60, 60, 30)},
+			want:         2,
+			wantDriver:   "cpu",
+		},
+		{
+			name:         "scale down blocked inside the hysteresis band",
+			current:      4,
+			observations: []RuleObservation{observe("cpu", config.DirectionBoth, 60, 20, 30)},
+			want:         4,
+			wantDriver:   "cpu",
+		},
+		{
+			name:         "clamped to max",
+			current:      4,
+			max:          8,
+			observations: []RuleObservation{observe("cpu", config.DirectionBoth, 60, 60, 300)},
+			want:         8,
+			wantDriver:   "cpu",
+		},
+		{
+			name:         "clamped to min",
+			current:      4,
+			min:          2,
+			observations: []RuleObservation{observe("cpu", config.DirectionBoth, 60, 60, 6)},
+			want:         2,
+			wantDriver:   "cpu",
+		},
+		{
+			name:    "highest rule drives",
+			current: 4,
+			observations: []RuleObservation{
+				observe("cpu", config.DirectionBoth, 60, 60, 90),
+				observe("memory", config.DirectionBoth, 60, 60, 75),
+			},
+			want:       6,
+			wantDriver: "cpu",
+		},
+		{
+			name:    "up-only rule does not block scale down",
+			current: 4,
+			observations: []RuleObservation{
+				observe("cpu", config.DirectionBoth, 60, 60, 30),
+				observe("queue", config.DirectionUp, 100, 100, 10),
+			},
+			want:       2,
+			wantDriver: "cpu",
+		},
+		{
+			name:    "down-only rule above threshold holds without scaling up",
+			current: 4,
+			observations: []RuleObservation{
+				observe("cpu", config.DirectionBoth, 60, 60, 30),
+				observe("memory", config.DirectionDown, 60, 60, 90),
+			},
+			want:       4,
+			wantDriver: "memory",
+		},
+		{
+			name:         "rule without data blocks scale down",
+			current:      4,
+			observations: []RuleObservation{observe("cpu", config.DirectionBoth, 60, 60, 30), unobserved},
+			want:         4,
+			wantDriver:   "cpu",
+		},
+		{
+			name:         "rule with weight 0 is ignored",
+			current:      4,
+			observations: []RuleObservation{observe("cpu", config.DirectionBoth, 60, 60, 60), weightless},
+			want:         4,
+			wantDriver:   "cpu",
+		},
+		{
+			name:         "no nodes",
+			current:      0,
+			observations: []RuleObservation{observe("cpu", config.DirectionBoth, 60, 60, 90)},
+			want:         0,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			target := config.Target{MinReplicas: 1, MaxReplicas: 10, TargetTrackingTolerance: 10}
+			if tt.min > 0 {
+				target.MinReplicas = tt.min
+			}
+			if tt.max > 0 {
+				target.MaxReplicas = tt.max
+			}
+			got, driver := desiredReplicas(target, tt.observations, tt.current)
+			if got != tt.want || driver != tt.wantDriver {
+				t.Errorf("desiredReplicas() = %d, %q, want %d, %q", got, driver, tt.want, tt.wantDriver)
+			}
+		})
+	}
+}
@@ -0,0 +1,161 @@
+// Package predict implementa a escala preditiva: perfis sazonais montados a
+// partir do histórico do Cloud Monitoring definem quantos nós o read pool
+// precisará nas próximas horas.
+package predict
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/heraque/alloydb-autoscaler/internal/alloydb"
+	"github.com/heraque/alloydb-autoscaler/internal/config"
+	"github.com/heraque/alloydb-autoscaler/internal/log"
+	"github.com/heraque/alloydb-autoscaler/internal/metrics"
+)
+
+// Forecast is the read pool size needed to keep the forecast load of every
+// predictive rule at or below its threshold
+type Forecast struct {
+	Replicas int
+	// Rule is the rule that requires the most nodes, and Load its forecast
+	// load in node-units of the rule's value
+	Rule string
+	Load float64
+	// At is the moment within the lookahead window with that load
+	At time.Time
+}
+
+// RetryInterval é o intervalo até a primeira nova tentativa depois de uma
+// reconstrução que falhou. A cada falha seguida o intervalo dobra, até
+// RefreshMinutes.
+var RetryInterval = time.Minute
+
+// Predictor mantém os perfis sazonais de um alvo e os reconstrói a cada
+// RefreshMinutes
+type Predictor struct {
+	source metrics.MetricSource
+
+	profiles map[string]*Profile
+	builtAt  time.Time
+	// settings identifica a configuração usada nos perfis atuais
+	settings string
+	// failures é o número de reconstruções seguidas que falharam e retryAt o
+	// momento da próxima tentativa
+	failures int
+	retryAt  time.Time
+}
+
+// NewPredictor cria um Predictor sem perfis; o primeiro Refresh os constrói
+func NewPredictor(source metrics.MetricSource) *Predictor {
+	return &Predictor{source: source}
+}
+
+// Refresh reconstrói os perfis quando o intervalo de atualização expirou ou a
+// configuração preditiva do alvo mudou. As consultas ao histórico têm prazo
+// próprio, HistoryTimeoutSeconds. Em caso de erro os perfis anteriores são
+// mantidos e a reconstrução é tentada de novo após RetryInterval, com backoff
+// exponencial. snapshot é usado nas conversões que dependem da instância,
+// como a memória total.
+func (p *Predictor) Refresh(ctx context.Context, snapshot alloydb.InstanceSnapshot, target config.Target, now time.Time) error {
+	rules := target.PredictiveRules()
+	settings := fmt.Sprintf("%v|%s|%v", target.Predictive, target.Timezone, rules)
+	refresh := time.Duration(target.Predictive.RefreshMinutes) * time.Minute
+	if settings == p.settings && now.Sub(p.builtAt) < refresh || now.Before(p.retryAt) {
+		return nil
+	}
+
+	startTime := time.Now()
+	ctx, cancel := context.WithTimeout(ctx, time.Duration(target.Predictive.HistoryTimeoutSeconds)*time.Second)
+	defer cancel()
+	profiles, err := p.build(ctx, snapshot, target, rules, now)
+	if err != nil {
+		p.failures++
+		delay := RetryInterval
+		for i := 1; i < p.failures && delay < refresh; i++ {
+			delay *= 2
+		}
+		delay = min(delay, refresh)
+		p.retryAt = now.Add(delay)
+		return fmt.Errorf("%w (attempt %d, retrying in %s)", err, p.failures, delay)
+	}
+	p.profiles = profiles
+	p.builtAt = now
+	p.settings = settings
+	p.failures = 0
+	p.retryAt = time.Time{}
+
+	event := log.Info().
+		Str("component", "predict").
+		Str("action", "refresh").
+		Str("target", target.Name).
+		Int("historyWeeks", target.Predictive.HistoryWeeks)
+	for name, profile := range profiles {
+		event = event.Int(name+"Samples", profile.Samples())
+	}
+	event.Str("duration", fmt.Sprintf("%.2fs", time.Since(startTime).Seconds())).
+		Msg("Seasonal profiles rebuilt")
+	return nil
+}
+
+// build consulta o histórico de HistoryWeeks semanas até now e monta um
+// perfil por regra
+func (p *Predictor) build(ctx context.Context, snapshot alloydb.InstanceSnapshot, target config.Target, rules []config.Rule, now time.Time) (map[string]*Profile, error) {
+	from := now.AddDate(0, 0, -7*target.Predictive.HistoryWeeks)
+	nodes, err := metrics.QueryNodeCountHistory(ctx, p.source, target, target.Predictive.NodeCountMetric, from, now)
+	if err != nil {
+		return nil, fmt.Errorf("error querying node count history: %w", err)
+	}
+	if len(nodes) == 0 {
+		return nil, fmt.Errorf("no history for node count metric %s", target.Predictive.NodeCountMetric)
+	}
+
+	profiles := make(map[string]*Profile, len(rules))
+	for _, rule := range rules {
+		values, err := metrics.QueryHistory(ctx, p.source, snapshot, target, rule, from, now)
+		if err != nil {
+			return nil, fmt.Errorf("error querying history of rule %s: %w", rule.Name, err)
+		}
+		profiles[rule.Name] = buildProfile(values, nodes, target.Location())
+	}
+	return profiles, nil
+}
+
+// Forecast devolve o maior número de nós exigido pelas regras preditivas entre
+// now e now+LookaheadMinutes. Retorna false quando não há perfil para o período.
+func (p *Predictor) Forecast(target config.Target, now time.Time) (Forecast, bool) {
+	lookahead := time.Duration(target.Predictive.LookaheadMinutes) * time.Minute
+	// O perfil é horário: basta avaliar now, o fim da janela e cada hora cheia
+	// entre os dois
+	moments := []time.Time{now}
+	for t := now.Truncate(time.Hour).Add(time.Hour); t.Before(now.Add(lookahead)); t = t.Add(time.Hour) {
+		moments = append(moments, t)
+	}
+	if lookahead > 0 {
+		moments = append(moments, now.Add(lookahead))
+	}
+
+	var (
+		best  Forecast
+		found bool
+	)
+	for _, rule := range target.PredictiveRules() {
+		profile := p.profiles[rule.Name]
+		if profile == nil || rule.Threshold <= 0 {
+			continue
+		}
+		for _, at := range moments {
+			load, ok := profile.Load(at)
+			if !ok {
+				continue
+			}
+			replicas := int(math.Ceil(load / rule.Threshold))
+			if !found || replicas > best.Replicas {
+				best = Forecast{Replicas: replicas, Rule: rule.Name, Load: load, At: at}
+				found = true
+			}
+		}
+	}
+	return best, found
+}
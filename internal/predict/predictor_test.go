@@ -0,0 +1,121 @@
+package predict
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/monitoring/apiv3/v2/monitoringpb"
+	"github.com/heraque/alloydb-autoscaler/internal/alloydb"
+	"github.com/heraque/alloydb-autoscaler/internal/config"
+	"github.com/heraque/alloydb-autoscaler/internal/metrics"
+)
+
+const nodeCountMetric = "custom.googleapis.com/read_pool/nodes"
+
+func TestRefreshRetryBackoff(t *testing.T) {
+	retryInterval := RetryInterval
+	t.Cleanup(func() { RetryInterval = retryInterval })
+	RetryInterval = time.Minute
+
+	target := config.Target{
+		Name:         t.Name(),
+		GCPProject:   "project",
+		ClusterName:  "cluster",
+		InstanceName: "read-pool",
+		Predictive: config.Predictive{
+			Enabled:               true,
+			HistoryWeeks:          1,
+			RefreshMinutes:        10,
+			NodeCountMetric:       nodeCountMetric,
+			HistoryTimeoutSeconds: 10,
+		},
+	}
+	// Sem séries da métrica de nós, a reconstrução falha por falta de histórico
+	source := metrics.NewFakeSource()
+	p := NewPredictor(source)
+	start := time.Date(2024, 6, 3, 12, 0, 0, 0, time.UTC)
+
+	// elapsed é o tempo desde start; wantErr é o sufixo esperado do erro,
+	// vazio quando Refresh não deve falhar
+	tests := []struct {
+		elapsed     time.Duration
+		withHistory bool
+		wantQuery   bool
+		wantErr     string
+	}{
+		{elapsed: 0, wantQuery: true, wantErr: "(attempt 1, retrying in 1m0s)"},
+		{elapsed: 30 * time.Second},
+		{elapsed: time.Minute, wantQuery: true, wantErr: "(attempt 2, retrying in 2m0s)"},
+		{elapsed: 2 * time.Minute},
+		{elapsed: 3 * time.Minute, wantQuery: true, wantErr: "(attempt 3, retrying in 4m0s)"},
+		{elapsed: 7 * time.Minute, wantQuery: true, wantErr: "(attempt 4, retrying in 8m0s)"},
+		// O intervalo não passa de RefreshMinutes
+		{elapsed: 15 * time.Minute, wantQuery: true, wantErr: "(attempt 5, retrying in 10m0s)"},
+		{elapsed: 25 * time.Minute, withHistory: true, wantQuery: true},
+		// Reconstruídos com sucesso, os perfis valem por RefreshMinutes
+		{elapsed: 26 * time.Minute, withHistory: true},
+		{elapsed: 34 * time.Minute, withHistory: true},
+		{elapsed: 35 * time.Minute, withHistory: true, wantQuery: true},
+	}
+	for _, tt := range tests {
+		if tt.withHistory {
+			source.SetSeries(nodeCountMetric, metrics.Int64Series(nil, 2, 2, 3))
+		}
+		requests := len(source.Requests())
+		err := p.Refresh(context.Background(), alloydb.InstanceSnapshot{}, target, start.Add(tt.elapsed))
+
+		if queried := len(source.Requests()) > requests; queried != tt.wantQuery {
+			t.Errorf("after %s: queried history = %v, want %v", tt.elapsed, queried, tt.wantQuery)
+		}
+		switch {
+		case tt.wantErr == "" && err != nil:
+			t.Errorf("after %s: Refresh() error = %v", tt.elapsed, err)
+		case tt.wantErr != "" && (err == nil || !strings.HasSuffix(err.Error(), tt.wantErr)):
+			t.Errorf("after %s: Refresh() error = %v, want suffix %q", tt.elapsed, err, tt.wantErr)
+		}
+	}
+}
+
+func TestRefreshHistoryTimeout(t *testing.T) {
+	target := config.Target{
+		Name:         t.Name(),
+		GCPProject:   "project",
+		ClusterName:  "cluster",
+		InstanceName: "read-pool",
+		Predictive: config.Predictive{
+			Enabled:               true,
+			HistoryWeeks:          1,
+			RefreshMinutes:        10,
+			NodeCountMetric:       nodeCountMetric,
+			HistoryTimeoutSeconds: 30,
+		},
+	}
+	source := &deadlineSource{FakeSource: metrics.NewFakeSource()}
+	source.SetSeries(nodeCountMetric, metrics.Int64Series(nil, 2))
+
+	// O prazo das consultas vem de HistoryTimeoutSeconds, não do contexto
+	// do chamador
+	ctx, cancel := context.WithTimeout(context.Background(), time.Hour)
+	defer cancel()
+	if err := NewPredictor(source).Refresh(ctx, alloydb.InstanceSnapshot{}, target, time.Now()); err != nil {
+		t.Fatalf("Refresh() error = %v", err)
+	}
+	if source.remaining <= 0 || source.remaining > 30*time.Second {
+		t.Errorf("history query deadline in %s, want at most 30s", source.remaining)
+	}
+}
+
+// deadlineSource registra o prazo restante da última consulta
+type deadlineSource struct {
+	*metrics.FakeSource
+	remaining time.Duration
+}
+
+func (s *deadlineSource) ListTimeSeries(ctx context.Context, req *monitoringpb.ListTimeSeriesRequest) ([]*monitoringpb.TimeSeries, error) {
+	if deadline, ok := ctx.Deadline(); ok {
+		s.remaining = time.Until(deadline)
+	}
+	return s.FakeSource.ListTimeSeries(ctx, req)
+}
@@ -0,0 +1,64 @@
+package predict
+
+import (
+	"time"
+
+	"github.com/heraque/alloydb-autoscaler/internal/metrics"
+)
+
+// Profile é o perfil sazonal de uma regra: a carga média de cada hora de cada
+// dia da semana, no fuso do alvo. A carga é o valor da regra multiplicado pelo
+// número de nós no mesmo instante, o que a torna independente do tamanho do
+// read pool: 4 nós a 60% e 2 nós a 120% são a mesma carga.
+type Profile struct {
+	loc   *time.Location
+	sum   [7][24]float64
+	count [7][24]int
+}
+
+// buildProfile combina o histórico da regra com o número de nós de cada hora.
+// Horas sem número de nós conhecido são descartadas.
+func buildProfile(values, nodes []metrics.HistoryPoint, loc *time.Location) *Profile {
+	nodesAt := make(map[int64]float64, len(nodes))
+	for _, n := range nodes {
+		nodesAt[n.Time.Unix()] = n.Value
+	}
+
+	p := &Profile{loc: loc}
+	for _, v := range values {
+		count := nodesAt[v.Time.Unix()]
+		if count <= 0 {
+			continue
+		}
+		day, hour := p.slot(v.Time)
+		p.sum[day][hour] += v.Value * count
+		p.count[day][hour]++
+	}
+	return p
+}
+
+// Load retorna a carga média prevista para a hora de t; false quando não há
+// histórico para essa hora
+func (p *Profile) Load(t time.Time) (float64, bool) {
+	day, hour := p.slot(t)
+	if p.count[day][hour] == 0 {
+		return 0, false
+	}
+	return p.sum[day][hour] / float64(p.count[day][hour]), true
+}
+
+// Samples retorna o número de horas do histórico usadas no perfil
+func (p *Profile) Samples() int {
+	total := 0
+	for day := range p.count {
+		for hour := range p.count[day] {
+			total += p.count[day][hour]
+		}
+	}
+	return total
+}
+
+func (p *Profile) slot(t time.Time) (int, int) {
+	t = t.In(p.loc)
+	return int(t.Weekday()), t.Hour()
+}
@@ -0,0 +1,159 @@
+package resilience
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/heraque/alloydb-autoscaler/internal/health"
+	"github.com/heraque/alloydb-autoscaler/internal/log"
+	"github.com/heraque/alloydb-autoscaler/internal/telemetry"
+)
+
+// Estados do circuit breaker
+const (
+	StateClosed   = "closed"
+	StateOpen     = "open"
+	StateHalfOpen = "half_open"
+)
+
+// BreakerThreshold é quantas chamadas seguidas com erro transitório ou de
+// cota abrem o circuito; BreakerOpenFor é por quanto tempo ele fica aberto
+// antes de deixar passar uma chamada de teste
+var (
+	BreakerThreshold = 5
+	BreakerOpenFor   = time.Minute
+)
+
+// ErrCircuitOpen é devolvido no lugar da chamada a uma API com o breaker aberto
+var ErrCircuitOpen = errors.New("circuit breaker open")
+
+// breaker acompanha as falhas seguidas de uma API. Erros de permissão, de
+// recurso inexistente e demais erros permanentes mostram que a API respondeu
+// e não contam como falha dela.
+type breaker struct {
+	api string
+
+	mu       sync.Mutex
+	state    string
+	failures int
+	since    time.Time
+	lastErr  string
+	probing  bool
+}
+
+var (
+	breakersMu sync.Mutex
+	breakers   = make(map[string]*breaker)
+)
+
+func breakerFor(api string) *breaker {
+	breakersMu.Lock()
+	defer breakersMu.Unlock()
+	b, ok := breakers[api]
+	if !ok {
+		b = &breaker{api: api, state: StateClosed, since: time.Now()}
+		breakers[api] = b
+	}
+	return b
+}
+
+// allow devolve ErrCircuitOpen enquanto o circuito estiver aberto. Passado
+// BreakerOpenFor, o circuito fica meio aberto e deixa passar uma única
+// chamada de teste.
+func (b *breaker) allow() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case StateOpen:
+		if time.Since(b.since) < BreakerOpenFor {
+			return fmt.Errorf("%w for %s API until %s", ErrCircuitOpen, b.api, b.since.Add(BreakerOpenFor).Format(time.RFC3339))
+		}
+		b.transition(StateHalfOpen)
+		b.probing = true
+	case StateHalfOpen:
+		if b.probing {
+			return fmt.Errorf("%w for %s API, waiting for the test call", ErrCircuitOpen, b.api)
+		}
+		b.probing = true
+	}
+	return nil
+}
+
+// open informa se o circuito está aberto ou aguardando a chamada de teste
+func (b *breaker) open() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.state != StateClosed
+}
+
+// record contabiliza o resultado de uma chamada liberada por allow
+func (b *breaker) record(err error, class Class) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.probing = false
+
+	if errors.Is(err, context.Canceled) {
+		// Chamada interrompida pelo próprio processo: nada se sabe sobre a API
+		return
+	}
+	if !class.Retryable() {
+		b.failures = 0
+		if b.state != StateClosed {
+			b.transition(StateClosed)
+		}
+		return
+	}
+
+	b.failures++
+	b.lastErr = describe(err)
+	if b.state == StateHalfOpen || b.state == StateClosed && b.failures >= BreakerThreshold {
+		b.transition(StateOpen)
+	}
+}
+
+// transition muda o estado, registrando a mudança em log, nos health checks
+// e nas métricas. Deve ser chamada com b.mu travado.
+func (b *breaker) transition(state string) {
+	previous := b.state
+	b.state = state
+	b.since = time.Now()
+	if state == StateClosed {
+		b.lastErr = ""
+	}
+
+	health.CircuitChanged(b.api, state, b.since, b.lastErr)
+	telemetry.SetCircuitOpen(b.api, state != StateClosed)
+
+	switch state {
+	case StateOpen:
+		log.Warn().
+			Str("component", "resilience").
+			Str("action", "circuit").
+			Str("api", b.api).
+			Str("state", state).
+			Str("previousState", previous).
+			Int("failures", b.failures).
+			Str("openFor", BreakerOpenFor.String()).
+			Str("lastError", b.lastErr).
+			Msg("Circuit breaker opened, pausing calls to the API")
+	case StateHalfOpen:
+		log.Info().
+			Str("component", "resilience").
+			Str("action", "circuit").
+			Str("api", b.api).
+			Str("state", state).
+			Msg("Circuit breaker half-open, sending a test call")
+	default:
+		log.Info().
+			Str("component", "resilience").
+			Str("action", "circuit").
+			Str("api", b.api).
+			Str("state", state).
+			Str("previousState", previous).
+			Msg("Circuit breaker closed, API calls resumed")
+	}
+}
@@ -0,0 +1,175 @@
+package resilience
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"google.golang.org/api/googleapi"
+)
+
+// step é uma chamada pelo circuit breaker: err é o resultado da API, caso a
+// chamada seja liberada
+type step struct {
+	err       error
+	wantAllow bool
+	wantState string
+}
+
+func TestBreaker(t *testing.T) {
+	unavailable := &googleapi.Error{Code: http.StatusServiceUnavailable}
+	denied := &googleapi.Error{Code: http.StatusForbidden}
+
+	tests := []struct {
+		name    string
+		openFor time.Duration
+		steps   []step
+	}{
+		{
+			name:    "opens after threshold failures",
+			openFor: time.Hour,
+			steps: []step{
+				{err: unavailable, wantAllow: true, wantState: StateClosed},
+				{err: unavailable, wantAllow: true, wantState: StateClosed},
+				{err: unavailable, wantAllow: true, wantState: StateOpen},
+				{wantAllow: false, wantState: StateOpen},
+			},
+		},
+		{
+			name:    "success resets the failure count",
+			openFor: time.Hour,
+			steps: []step{
+				{err: unavailable, wantAllow: true, wantState: StateClosed},
+				{err: unavailable, wantAllow: true, wantState: StateClosed},
+				{err: nil, wantAllow: true, wantState: StateClosed},
+				{err: unavailable, wantAllow: true, wantState: StateClosed},
+				{err: unavailable, wantAllow: true, wantState: StateClosed},
+			},
+		},
+		{
+			name:    "permanent errors do not count",
+			openFor: time.Hour,
+			steps: []step{
+				{err: denied, wantAllow: true, wantState: StateClosed},
+				{err: denied, wantAllow: true, wantState: StateClosed},
+				{err: denied, wantAllow: true, wantState: StateClosed},
+				{err: denied, wantAllow: true, wantState: StateClosed},
+			},
+		},
+		{
+			name:    "cancellation does not count",
+			openFor: time.Hour,
+			steps: []step{
+				{err: unavailable, wantAllow: true, wantState: StateClosed},
+				{err: unavailable, wantAllow: true, wantState: StateClosed},
+				{err: context.Canceled, wantAllow: true, wantState: StateClosed},
+				{err: unavailable, wantAllow: true, wantState: StateOpen},
+			},
+		},
+		{
+			name: "successful test call closes",
+			steps: []step{
+				{err: unavailable, wantAllow: true, wantState: StateClosed},
+				{err: unavailable, wantAllow: true, wantState: StateClosed},
+				{err: unavailable, wantAllow: true, wantState: StateOpen},
+				{err: nil, wantAllow: true, wantState: StateClosed},
+			},
+		},
+		{
+			name: "failed test call reopens",
+			steps: []step{
+				{err: unavailable, wantAllow: true, wantState: StateClosed},
+				{err: unavailable, wantAllow: true, wantState: StateClosed},
+				{err: unavailable, wantAllow: true, wantState: StateOpen},
+				{err: unavailable, wantAllow: true, wantState: StateOpen},
+			},
+		},
+		{
+			name: "permanent error on the test call closes",
+			steps: []step{
+				{err: unavailable, wantAllow: true, wantState: StateClosed},
+				{err: unavailable, wantAllow: true, wantState: StateClosed},
+				{err: unavailable, wantAllow: true, wantState: StateOpen},
+				{err: denied, wantAllow: true, wantState: StateClosed},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			threshold, openFor := BreakerThreshold, BreakerOpenFor
+			t.Cleanup(func() { BreakerThreshold, BreakerOpenFor = threshold, openFor })
+			BreakerThreshold, BreakerOpenFor = 3, tt.openFor
+
+			b := breakerFor(testAPI(t))
+			for i, s := range tt.steps {
+				err := b.allow()
+				if allowed := err == nil; allowed != s.wantAllow {
+					t.Fatalf("step %d: allow() = %v, want allowed %v", i, err, s.wantAllow)
+				}
+				if err == nil {
+					b.record(s.err, Classify(s.err))
+				} else if !errors.Is(err, ErrCircuitOpen) {
+					t.Fatalf("step %d: allow() = %v, want ErrCircuitOpen", i, err)
+				}
+				if b.state != s.wantState {
+					t.Fatalf("step %d: state = %s, want %s", i, b.state, s.wantState)
+				}
+			}
+		})
+	}
+}
+
+func TestBreakerSingleTestCall(t *testing.T) {
+	threshold, openFor := BreakerThreshold, BreakerOpenFor
+	t.Cleanup(func() { BreakerThreshold, BreakerOpenFor = threshold, openFor })
+	BreakerThreshold, BreakerOpenFor = 1, 0
+
+	b := breakerFor(testAPI(t))
+	if err := b.allow(); err != nil {
+		t.Fatal(err)
+	}
+	b.record(&googleapi.Error{Code: http.StatusServiceUnavailable}, ClassRetryable)
+
+	// A primeira chamada depois de BreakerOpenFor é a de teste; as demais
+	// aguardam o resultado dela
+	if err := b.allow(); err != nil {
+		t.Fatalf("test call allow() = %v", err)
+	}
+	if b.state != StateHalfOpen {
+		t.Errorf("state = %s, want %s", b.state, StateHalfOpen)
+	}
+	if err := b.allow(); !errors.Is(err, ErrCircuitOpen) {
+		t.Errorf("concurrent call allow() = %v, want ErrCircuitOpen", err)
+	}
+}
+
+func TestCallCircuitOpen(t *testing.T) {
+	noBackoff(t)
+	threshold, openFor := BreakerThreshold, BreakerOpenFor
+	t.Cleanup(func() { BreakerThreshold, BreakerOpenFor = threshold, openFor })
+	BreakerThreshold, BreakerOpenFor = 2, time.Hour
+
+	api := testAPI(t)
+	calls := 0
+	fn := func(context.Context) (struct{}, error) {
+		calls++
+		return struct{}{}, &googleapi.Error{Code: http.StatusServiceUnavailable}
+	}
+	// A segunda falha abre o circuito e interrompe as novas tentativas
+	if _, err := Call(context.Background(), api, "Get", true, fn); err == nil {
+		t.Fatal("Call() error = nil")
+	}
+	if calls != 2 {
+		t.Errorf("calls = %d, want 2 before the circuit opened", calls)
+	}
+
+	_, err := Call(context.Background(), api, "Get", true, fn)
+	if Classify(err) != ClassCircuitOpen {
+		t.Errorf("Call() error = %v, want ErrCircuitOpen", err)
+	}
+	if calls != 2 {
+		t.Errorf("calls = %d, want no call while the circuit is open", calls)
+	}
+}
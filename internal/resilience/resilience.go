@@ -0,0 +1,204 @@
+// Package resilience classifica os erros das APIs da GCP, repete as chamadas
+// em que a repetição é segura e interrompe as chamadas a uma API com falhas
+// persistentes por meio de um circuit breaker.
+package resilience
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/heraque/alloydb-autoscaler/internal/log"
+	"google.golang.org/api/googleapi"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Class é a categoria de um erro de API, que decide se a chamada é repetida e
+// se conta como falha da API
+type Class string
+
+const (
+	// ClassRetryable cobre falhas transitórias: 5xx, UNAVAILABLE, timeouts e
+	// erros de rede
+	ClassRetryable Class = "retryable"
+	// ClassQuota cobre 429 e RESOURCE_EXHAUSTED; repetido com backoff maior
+	ClassQuota Class = "quota"
+	// ClassPermission cobre 401/403 e PERMISSION_DENIED/UNAUTHENTICATED
+	ClassPermission Class = "permission"
+	// ClassNotFound cobre 404 e NOT_FOUND
+	ClassNotFound Class = "not_found"
+	// ClassCircuitOpen é devolvido sem chamar a API enquanto o breaker dela
+	// está aberto
+	ClassCircuitOpen Class = "circuit_open"
+	// ClassPermanent cobre todos os demais erros
+	ClassPermanent Class = "permanent"
+)
+
+// Retryable informa se uma nova tentativa pode ter resultado diferente
+func (c Class) Retryable() bool {
+	return c == ClassRetryable || c == ClassQuota
+}
+
+// Policy define as novas tentativas de uma chamada
+type Policy struct {
+	// MaxAttempts inclui a primeira tentativa
+	MaxAttempts    int
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+	// QuotaMultiplier aumenta o intervalo após erros de cota, que costumam
+	// valer por uma janela de um minuto
+	QuotaMultiplier int
+}
+
+// DefaultPolicy é usada em todas as chamadas às APIs da GCP
+var DefaultPolicy = Policy{
+	MaxAttempts:     3,
+	InitialBackoff:  500 * time.Millisecond,
+	MaxBackoff:      10 * time.Second,
+	QuotaMultiplier: 4,
+}
+
+// Classify devolve a classe de err. Reconhece erros da API REST do AlloyDB
+// (googleapi) e da API gRPC do Cloud Monitoring, mesmo quando encapsulados.
+func Classify(err error) Class {
+	if err == nil {
+		return ""
+	}
+	if errors.Is(err, ErrCircuitOpen) {
+		return ClassCircuitOpen
+	}
+	if errors.Is(err, context.Canceled) {
+		return ClassPermanent
+	}
+
+	var apiErr *googleapi.Error
+	if errors.As(err, &apiErr) {
+		return classifyHTTP(apiErr)
+	}
+	if s, ok := status.FromError(err); ok {
+		return classifyGRPC(s.Code())
+	}
+
+	var netErr net.Error
+	if errors.Is(err, context.DeadlineExceeded) || errors.As(err, &netErr) {
+		return ClassRetryable
+	}
+	return ClassPermanent
+}
+
+func classifyHTTP(err *googleapi.Error) Class {
+	switch err.Code {
+	case http.StatusTooManyRequests:
+		return ClassQuota
+	case http.StatusInternalServerError, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return ClassRetryable
+	case http.StatusUnauthorized:
+		return ClassPermission
+	case http.StatusForbidden:
+		// Algumas APIs respondem 403 quando a cota é excedida
+		for _, item := range err.Errors {
+			if item.Reason == "rateLimitExceeded" || item.Reason == "quotaExceeded" {
+				return ClassQuota
+			}
+		}
+		return ClassPermission
+	case http.StatusNotFound:
+		return ClassNotFound
+	}
+	return ClassPermanent
+}
+
+func classifyGRPC(code codes.Code) Class {
+	switch code {
+	case codes.ResourceExhausted:
+		return ClassQuota
+	case codes.Unavailable, codes.DeadlineExceeded, codes.Aborted, codes.Internal:
+		return ClassRetryable
+	case codes.PermissionDenied, codes.Unauthenticated:
+		return ClassPermission
+	case codes.NotFound:
+		return ClassNotFound
+	}
+	return ClassPermanent
+}
+
+// Call executa fn pelo circuit breaker de api. Com retry, erros transitórios
+// e de cota são repetidos até Policy.MaxAttempts vezes, com backoff
+// exponencial e variação aleatória; sem retry, usado em chamadas que não são
+// seguras de repetir, fn é executada uma única vez. O erro devolvido é o da
+// última tentativa, sem alterações, ou ErrCircuitOpen.
+func Call[T any](ctx context.Context, api, method string, retry bool, fn func(context.Context) (T, error)) (T, error) {
+	policy := DefaultPolicy
+	attempts := 1
+	if retry {
+		attempts = max(policy.MaxAttempts, 1)
+	}
+
+	breaker := breakerFor(api)
+	for attempt := 1; ; attempt++ {
+		if err := breaker.allow(); err != nil {
+			var zero T
+			return zero, err
+		}
+		result, err := fn(ctx)
+		class := Classify(err)
+		breaker.record(err, class)
+		if err == nil || attempt >= attempts || !class.Retryable() || ctx.Err() != nil || breaker.open() {
+			return result, err
+		}
+
+		delay := policy.backoff(attempt, class)
+		if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < delay {
+			// Não há tempo para outra tentativa dentro do prazo do chamador
+			return result, err
+		}
+		log.Warn().
+			Str("component", "resilience").
+			Str("action", "retry").
+			Str("api", api).
+			Str("method", method).
+			Int("attempt", attempt).
+			Int("maxAttempts", attempts).
+			Str("errorClass", string(class)).
+			Str("backoff", delay.Round(time.Millisecond).String()).
+			Str("error", err.Error()).
+			Msg("GCP API call failed, retrying")
+
+		select {
+		case <-ctx.Done():
+			return result, err
+		case <-time.After(delay):
+		}
+	}
+}
+
+// backoff devolve o intervalo antes da tentativa seguinte à attempt, com ±20%
+// de variação aleatória
+func (p Policy) backoff(attempt int, class Class) time.Duration {
+	delay := p.InitialBackoff
+	for i := 1; i < attempt && delay < p.MaxBackoff; i++ {
+		delay *= 2
+	}
+	if class == ClassQuota && p.QuotaMultiplier > 1 {
+		delay *= time.Duration(p.QuotaMultiplier)
+	}
+	delay = min(delay, p.MaxBackoff)
+	if delay <= 0 {
+		return 0
+	}
+	jitter := delay / 5
+	return delay - jitter + rand.N(2*jitter+1)
+}
+
+// describe resume um erro para logs e health checks
+func describe(err error) string {
+	if err == nil {
+		return ""
+	}
+	return fmt.Sprintf("%s: %v", Classify(err), err)
+}
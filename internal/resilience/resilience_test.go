@@ -0,0 +1,166 @@
+package resilience
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"google.golang.org/api/googleapi"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestClassify(t *testing.T) {
+	quota403 := &googleapi.Error{
+		Code:   http.StatusForbidden,
+		Errors: []googleapi.ErrorItem{{Reason: "rateLimitExceeded"}},
+	}
+	tests := []struct {
+		name string
+		err  error
+		want Class
+	}{
+		{name: "nil", err: nil, want: ""},
+		{name: "circuit open", err: fmt.Errorf("%w for alloydb API", ErrCircuitOpen), want: ClassCircuitOpen},
+		{name: "canceled", err: context.Canceled, want: ClassPermanent},
+		{name: "deadline exceeded", err: fmt.Errorf("get instance: %w", context.DeadlineExceeded), want: ClassRetryable},
+		{name: "network error", err: &net.OpError{Op: "dial", Err: errors.New("connection refused")}, want: ClassRetryable},
+		{name: "http 429", err: &googleapi.Error{Code: http.StatusTooManyRequests}, want: ClassQuota},
+		{name: "http 500", err: &googleapi.Error{Code: http.StatusInternalServerError}, want: ClassRetryable},
+		{name: "http 503 wrapped", err: fmt.Errorf("patch: %w", &googleapi.Error{Code: http.StatusServiceUnavailable}), want: ClassRetryable},
+		{name: "http 401", err: &googleapi.Error{Code: http.StatusUnauthorized}, want: ClassPermission},
+		{name: "http 403", err: &googleapi.Error{Code: http.StatusForbidden}, want: ClassPermission},
+		{name: "http 403 rate limit", err: quota403, want: ClassQuota},
+		{name: "http 404", err: &googleapi.Error{Code: http.StatusNotFound}, want: ClassNotFound},
+		{name: "http 400", err: &googleapi.Error{Code: http.StatusBadRequest}, want: ClassPermanent},
+		{name: "http 409", err: &googleapi.Error{Code: http.StatusConflict}, want: ClassPermanent},
+		{name: "grpc resource exhausted", err: status.Error(codes.ResourceExhausted, "quota"), want: ClassQuota},
+		{name: "grpc unavailable", err: status.Error(codes.Unavailable, "unavailable"), want: ClassRetryable},
+		{name: "grpc internal", err: status.Error(codes.Internal, "internal"), want: ClassRetryable},
+		{name: "grpc permission denied", err: status.Error(codes.PermissionDenied, "denied"), want: ClassPermission},
+		{name: "grpc unauthenticated", err: status.Error(codes.Unauthenticated, "token"), want: ClassPermission},
+		{name: "grpc not found", err: status.Error(codes.NotFound, "metric"), want: ClassNotFound},
+		{name: "grpc invalid argument", err: status.Error(codes.InvalidArgument, "filter"), want: ClassPermanent},
+		{name: "grpc wrapped", err: fmt.Errorf("list time series: %w", status.Error(codes.Unavailable, "unavailable")), want: ClassRetryable},
+		{name: "plain error", err: errors.New("boom"), want: ClassPermanent},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Classify(tt.err); got != tt.want {
+				t.Errorf("Classify(%v) = %q, want %q", tt.err, got, tt.want)
+			}
+		})
+	}
+}
+
+// testAPI devolve um nome de API exclusivo do teste e descarta o circuit
+// breaker dele ao final, para que execuções repetidas comecem fechadas
+func testAPI(t *testing.T) string {
+	t.Helper()
+	api := t.Name()
+	t.Cleanup(func() {
+		breakersMu.Lock()
+		defer breakersMu.Unlock()
+		delete(breakers, api)
+	})
+	return api
+}
+
+// noBackoff zera os intervalos entre tentativas durante o teste
+func noBackoff(t *testing.T) {
+	t.Helper()
+	policy := DefaultPolicy
+	t.Cleanup(func() { DefaultPolicy = policy })
+	DefaultPolicy.InitialBackoff = 0
+	DefaultPolicy.MaxBackoff = 0
+}
+
+func TestCall(t *testing.T) {
+	unavailable := &googleapi.Error{Code: http.StatusServiceUnavailable}
+	quota := &googleapi.Error{Code: http.StatusTooManyRequests}
+	denied := &googleapi.Error{Code: http.StatusForbidden}
+
+	tests := []struct {
+		name      string
+		retry     bool
+		errs      []error
+		wantCalls int
+		wantErr   error
+	}{
+		{name: "success", retry: true, errs: []error{nil}, wantCalls: 1},
+		{name: "transient error retried", retry: true, errs: []error{unavailable, nil}, wantCalls: 2},
+		{name: "quota error retried", retry: true, errs: []error{quota, quota, nil}, wantCalls: 3},
+		{name: "gives up after max attempts", retry: true, errs: []error{unavailable, unavailable, unavailable, nil}, wantCalls: 3, wantErr: unavailable},
+		{name: "permanent error not retried", retry: true, errs: []error{denied, nil}, wantCalls: 1, wantErr: denied},
+		{name: "no retry for unsafe calls", retry: false, errs: []error{unavailable, nil}, wantCalls: 1, wantErr: unavailable},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			noBackoff(t)
+			calls := 0
+			got, err := Call(context.Background(), testAPI(t), "Get", tt.retry, func(context.Context) (int, error) {
+				err := tt.errs[calls]
+				calls++
+				if err != nil {
+					return 0, err
+				}
+				return calls, nil
+			})
+			if calls != tt.wantCalls {
+				t.Errorf("calls = %d, want %d", calls, tt.wantCalls)
+			}
+			if !errors.Is(err, tt.wantErr) || (err == nil) != (tt.wantErr == nil) {
+				t.Errorf("Call() error = %v, want %v", err, tt.wantErr)
+			}
+			if err == nil && got != calls {
+				t.Errorf("Call() = %d, want the result of call %d", got, calls)
+			}
+		})
+	}
+}
+
+func TestCallStopsWhenDeadlineIsTooClose(t *testing.T) {
+	policy := DefaultPolicy
+	t.Cleanup(func() { DefaultPolicy = policy })
+	DefaultPolicy.InitialBackoff = time.Minute
+	DefaultPolicy.MaxBackoff = time.Minute
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	calls := 0
+	_, err := Call(ctx, testAPI(t), "Get", true, func(context.Context) (struct{}, error) {
+		calls++
+		return struct{}{}, &googleapi.Error{Code: http.StatusServiceUnavailable}
+	})
+	if err == nil || calls != 1 {
+		t.Errorf("Call() = %v after %d calls, want the first error without waiting past the deadline", err, calls)
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	policy := Policy{InitialBackoff: time.Second, MaxBackoff: 10 * time.Second, QuotaMultiplier: 4}
+	tests := []struct {
+		attempt int
+		class   Class
+		base    time.Duration
+	}{
+		{attempt: 1, class: ClassRetryable, base: time.Second},
+		{attempt: 2, class: ClassRetryable, base: 2 * time.Second},
+		{attempt: 3, class: ClassRetryable, base: 4 * time.Second},
+		{attempt: 10, class: ClassRetryable, base: 10 * time.Second},
+		{attempt: 1, class: ClassQuota, base: 4 * time.Second},
+		{attempt: 3, class: ClassQuota, base: 10 * time.Second},
+	}
+	for _, tt := range tests {
+		for range 50 {
+			delay := policy.backoff(tt.attempt, tt.class)
+			if low, high := tt.base-tt.base/5, tt.base+tt.base/5; delay < low || delay > high {
+				t.Fatalf("backoff(%d, %s) = %s, want between %s and %s", tt.attempt, tt.class, delay, low, high)
+			}
+		}
+	}
+}
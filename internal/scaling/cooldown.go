@@ -0,0 +1,71 @@
+package scaling
+
+import (
+	"sync"
+	"time"
+
+	"github.com/heraque/alloydb-autoscaler/internal/config"
+)
+
+// completion é a última operação de escala concluída de um alvo
+type completion struct {
+	action string
+	at     time.Time
+}
+
+var (
+	completionsMu sync.Mutex
+	completions   = make(map[string]completion)
+)
+
+// Cooldown descreve uma janela de cooldown ativa
+type Cooldown struct {
+	// LastAction é a ação de escala cujo término iniciou a janela
+	LastAction  string
+	CompletedAt time.Time
+	Remaining   time.Duration
+}
+
+// recordCompletion inicia o cooldown de action a partir do término da operação
+func recordCompletion(target, action string, at time.Time) {
+	completionsMu.Lock()
+	defer completionsMu.Unlock()
+	completions[target] = completion{action: action, at: at}
+}
+
+// ActiveCooldown devolve o cooldown que suprime action no alvo, se houver. A
+// janela é ScaleUpCooldown ou ScaleDownCooldown, conforme a última ação
+// concluída; com CooldownOpposite, suprime apenas a direção oposta.
+func ActiveCooldown(target config.Target, action string) (Cooldown, bool) {
+	completionsMu.Lock()
+	last, ok := completions[target.Name]
+	completionsMu.Unlock()
+	if !ok {
+		return Cooldown{}, false
+	}
+	if action == last.action && target.CooldownScope != config.CooldownAll {
+		return Cooldown{}, false
+	}
+
+	window := target.ScaleDownCooldown
+	if last.action == "scaleUp" {
+		window = target.ScaleUpCooldown
+	}
+	remaining := time.Until(last.at.Add(time.Duration(window) * time.Second))
+	if remaining <= 0 {
+		return Cooldown{}, false
+	}
+	return Cooldown{LastAction: last.action, CompletedAt: last.at, Remaining: remaining}, true
+}
+
+// Forget descarta o histórico e os registros de dry-run de um alvo que
+// deixou de ser gerenciado
+func Forget(target string) {
+	completionsMu.Lock()
+	delete(completions, target)
+	completionsMu.Unlock()
+
+	dryRunMu.Lock()
+	delete(dryRunRecords, target)
+	dryRunMu.Unlock()
+}
@@ -0,0 +1,126 @@
+package scaling
+
+import (
+	"testing"
+	"time"
+
+	"github.com/heraque/alloydb-autoscaler/internal/config"
+)
+
+func TestActiveCooldown(t *testing.T) {
+	tests := []struct {
+		name          string
+		scope         string
+		lastAction    string
+		completedAgo  time.Duration
+		upCooldown    int
+		downCooldown  int
+		action        string
+		wantActive    bool
+		wantRemaining time.Duration
+	}{
+		{
+			name:   "no completed operation",
+			scope:  config.CooldownOpposite,
+			action: "scaleUp",
+		},
+		{
+			name:          "opposite scope suppresses the opposite direction",
+			scope:         config.CooldownOpposite,
+			lastAction:    "scaleUp",
+			completedAgo:  time.Minute,
+			upCooldown:    300,
+			downCooldown:  60,
+			action:        "scaleDown",
+			wantActive:    true,
+			wantRemaining: 4 * time.Minute,
+		},
+		{
+			name:         "opposite scope allows the same direction",
+			scope:        config.CooldownOpposite,
+			lastAction:   "scaleUp",
+			completedAgo: time.Minute,
+			upCooldown:   300,
+			action:       "scaleUp",
+		},
+		{
+			name:          "all scope suppresses the same direction",
+			scope:         config.CooldownAll,
+			lastAction:    "scaleUp",
+			completedAgo:  time.Minute,
+			upCooldown:    300,
+			action:        "scaleUp",
+			wantActive:    true,
+			wantRemaining: 4 * time.Minute,
+		},
+		{
+			name:          "window of the last completed action",
+			scope:         config.CooldownAll,
+			lastAction:    "scaleDown",
+			completedAgo:  time.Minute,
+			upCooldown:    60,
+			downCooldown:  600,
+			action:        "scaleUp",
+			wantActive:    true,
+			wantRemaining: 9 * time.Minute,
+		},
+		{
+			name:         "expired window",
+			scope:        config.CooldownAll,
+			lastAction:   "scaleUp",
+			completedAgo: 2 * time.Minute,
+			upCooldown:   60,
+			action:       "scaleDown",
+		},
+		{
+			name:         "no window for the last action",
+			scope:        config.CooldownAll,
+			lastAction:   "scaleDown",
+			completedAgo: time.Second,
+			upCooldown:   600,
+			action:       "scaleUp",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Cleanup(func() { Forget(t.Name()) })
+			target := config.Target{
+				Name:              t.Name(),
+				CooldownScope:     tt.scope,
+				ScaleUpCooldown:   tt.upCooldown,
+				ScaleDownCooldown: tt.downCooldown,
+			}
+			completedAt := time.Now().Add(-tt.completedAgo)
+			if tt.lastAction != "" {
+				recordCompletion(target.Name, tt.lastAction, completedAt)
+			}
+
+			cooldown, active := ActiveCooldown(target, tt.action)
+			if active != tt.wantActive {
+				t.Fatalf("ActiveCooldown() active = %v, want %v", active, tt.wantActive)
+			}
+			if !active {
+				return
+			}
+			if cooldown.LastAction != tt.lastAction || !cooldown.CompletedAt.Equal(completedAt) {
+				t.Errorf("ActiveCooldown() = %+v, want the %s completed at %v", cooldown, tt.lastAction, completedAt)
+			}
+			if diff := tt.wantRemaining - cooldown.Remaining; diff < 0 || diff > time.Second {
+				t.Errorf("Remaining = %v, want about %v", cooldown.Remaining, tt.wantRemaining)
+			}
+		})
+	}
+}
+
+func TestForgetCooldown(t *testing.T) {
+	target := config.Target{Name: t.Name(), CooldownScope: config.CooldownAll, ScaleUpCooldown: 300}
+	recordCompletion(target.Name, "scaleUp", time.Now())
+	if _, active := ActiveCooldown(target, "scaleUp"); !active {
+		t.Fatal("ActiveCooldown() active = false right after a completed operation")
+	}
+
+	Forget(target.Name)
+	if cooldown, active := ActiveCooldown(target, "scaleUp"); active {
+		t.Errorf("ActiveCooldown() = %+v after Forget, want none", cooldown)
+	}
+}
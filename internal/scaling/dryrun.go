@@ -0,0 +1,101 @@
+package scaling
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/heraque/alloydb-autoscaler/internal/config"
+	"github.com/heraque/alloydb-autoscaler/internal/log"
+)
+
+// maxDryRunRecords limita quantos registros de dry-run de cada alvo ficam em memória
+const maxDryRunRecords = 500
+
+// DryRunRecord descreve o PATCH que teria sido enviado em modo dry-run
+type DryRunRecord struct {
+	Time         time.Time `json:"time"`
+	Target       string    `json:"target"`
+	Instance     string    `json:"instance"`
+	Action       string    `json:"action"`
+	CurrentCount int       `json:"currentCount"`
+	TargetCount  int       `json:"targetCount"`
+	Reason       string    `json:"reason"`
+}
+
+var (
+	dryRunMu      sync.Mutex
+	dryRunRecords = make(map[string][]DryRunRecord)
+)
+
+// DryRunRecords retorna os registros de dry-run mais recentes do alvo, do
+// mais antigo para o mais novo
+func DryRunRecords(target string) []DryRunRecord {
+	dryRunMu.Lock()
+	defer dryRunMu.Unlock()
+	return append([]DryRunRecord(nil), dryRunRecords[target]...)
+}
+
+// recordDryRun registra a decisão em memória, no log e, se configurado, no
+// arquivo DRY_RUN_LOG (uma linha JSON por registro) para comparação posterior
+func recordDryRun(target config.Target, action string, currentCount, targetCount int, reason string) {
+	record := DryRunRecord{
+		Time:         time.Now(),
+		Target:       target.Name,
+		Instance:     target.InstancePath(),
+		Action:       action,
+		CurrentCount: currentCount,
+		TargetCount:  targetCount,
+		Reason:       reason,
+	}
+
+	dryRunMu.Lock()
+	defer dryRunMu.Unlock()
+
+	records := append(dryRunRecords[target.Name], record)
+	if len(records) > maxDryRunRecords {
+		records = records[len(records)-maxDryRunRecords:]
+	}
+	dryRunRecords[target.Name] = records
+
+	log.Info().
+		Str("component", "scaling").
+		Str("action", action).
+		Str("target", target.Name).
+		Str("instance", target.InstanceName).
+		Int("currentReplicas", currentCount).
+		Int("targetReplicas", targetCount).
+		Str("reason", reason).
+		Bool("dryRun", true).
+		Msg("Dry run: replica update not sent")
+
+	if path := config.Get().DryRunLog; path != "" {
+		if err := appendDryRunRecord(path, record); err != nil {
+			log.Error(err).
+				Str("component", "scaling").
+				Str("action", action).
+				Str("target", target.Name).
+				Str("path", path).
+				Msg("Failed to write dry run record")
+		}
+	}
+}
+
+func appendDryRunRecord(path string, record DryRunRecord) error {
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return fmt.Errorf("error opening dry run log: %w", err)
+	}
+	defer file.Close()
+
+	data, err := json.Marshal(record)
+	if err != nil {
+		return fmt.Errorf("error encoding dry run record: %w", err)
+	}
+	if _, err := file.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("error writing dry run record: %w", err)
+	}
+	return nil
+}
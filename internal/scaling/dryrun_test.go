@@ -0,0 +1,147 @@
+package scaling
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/heraque/alloydb-autoscaler/internal/alloydb"
+	"github.com/heraque/alloydb-autoscaler/internal/config"
+)
+
+func TestDryRun(t *testing.T) {
+	tests := []struct {
+		name       string
+		nodes      int
+		scale      func(context.Context, alloydb.InstanceAPI, alloydb.InstanceSnapshot, config.Target) error
+		wantAction string
+		wantCount  int
+	}{
+		{
+			name:  "scale up",
+			nodes: 2,
+			scale: func(ctx context.Context, api alloydb.InstanceAPI, snapshot alloydb.InstanceSnapshot, target config.Target) error {
+				return ScaleUp(ctx, api, snapshot, target, 0, "test")
+			},
+			wantAction: "scaleUp",
+			wantCount:  3,
+		},
+		{
+			name:  "scale down",
+			nodes: 3,
+			scale: func(ctx context.Context, api alloydb.InstanceAPI, snapshot alloydb.InstanceSnapshot, target config.Target) error {
+				return ScaleDown(ctx, api, snapshot, target, 0, "test")
+			},
+			wantAction: "scaleDown",
+			wantCount:  2,
+		},
+		{
+			name:  "scale to",
+			nodes: 1,
+			scale: func(ctx context.Context, api alloydb.InstanceAPI, snapshot alloydb.InstanceSnapshot, target config.Target) error {
+				return ScaleTo(ctx, api, snapshot, target, 4, "test")
+			},
+			wantAction: "scaleUp",
+			wantCount:  4,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			api := alloydb.NewFake()
+			target := newTarget(t, api, tt.nodes)
+			target.DryRun = true
+			target.ScaleUpCooldown = 60
+			target.ScaleDownCooldown = 60
+
+			if err := tt.scale(context.Background(), api, newSnapshot(t, api, target), target); err != nil {
+				t.Fatalf("scale error = %v", err)
+			}
+			if patches := api.Patches(); len(patches) != 0 {
+				t.Errorf("patches = %+v, want none in dry run", patches)
+			}
+			if got := api.NodeCount(target.InstancePath()); got != tt.nodes {
+				t.Errorf("node count = %d, want %d", got, tt.nodes)
+			}
+
+			records := DryRunRecords(target.Name)
+			if len(records) != 1 {
+				t.Fatalf("dry run records = %+v, want 1", records)
+			}
+			record := records[0]
+			if record.Action != tt.wantAction || record.CurrentCount != tt.nodes || record.TargetCount != tt.wantCount {
+				t.Errorf("record = %+v, want %s from %d to %d", record, tt.wantAction, tt.nodes, tt.wantCount)
+			}
+			if record.Instance != target.InstancePath() || record.Reason != "test" {
+				t.Errorf("record instance/reason = %q/%q, want %q/test", record.Instance, record.Reason, target.InstancePath())
+			}
+			opposite := "scaleDown"
+			if tt.wantAction == "scaleDown" {
+				opposite = "scaleUp"
+			}
+			if _, active := ActiveCooldown(target, opposite); !active {
+				t.Errorf("dry run %s did not start the cooldown for %s", tt.wantAction, opposite)
+			}
+		})
+	}
+}
+
+func TestDryRunLog(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "dry-run.jsonl")
+	previous := config.Get()
+	c := previous
+	c.DryRunLog = path
+	config.Set(c)
+	t.Cleanup(func() { config.Set(previous) })
+
+	api := alloydb.NewFake()
+	target := newTarget(t, api, 2)
+	target.DryRun = true
+	for range 2 {
+		if err := ScaleUp(context.Background(), api, newSnapshot(t, api, target), target, 0, "test"); err != nil {
+			t.Fatalf("ScaleUp() error = %v", err)
+		}
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	var lines int
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var record DryRunRecord
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			t.Fatalf("line %d: %v", lines+1, err)
+		}
+		if record.Target != target.Name || record.TargetCount != 3 {
+			t.Errorf("line %d = %+v, want %s to 3 nodes", lines+1, record, target.Name)
+		}
+		lines++
+	}
+	if err := scanner.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if lines != 2 {
+		t.Errorf("dry run log lines = %d, want 2", lines)
+	}
+}
+
+func TestForgetDropsDryRunRecords(t *testing.T) {
+	api := alloydb.NewFake()
+	target := newTarget(t, api, 2)
+	target.DryRun = true
+	if err := ScaleUp(context.Background(), api, newSnapshot(t, api, target), target, 0, "test"); err != nil {
+		t.Fatalf("ScaleUp() error = %v", err)
+	}
+
+	Forget(target.Name)
+
+	if records := DryRunRecords(target.Name); len(records) != 0 {
+		t.Errorf("dry run records after Forget = %+v, want none", records)
+	}
+}
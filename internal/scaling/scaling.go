@@ -2,110 +2,268 @@ package scaling
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"time"
 
 	"github.com/heraque/alloydb-autoscaler/internal/alloydb"
 	"github.com/heraque/alloydb-autoscaler/internal/config"
 	"github.com/heraque/alloydb-autoscaler/internal/log"
+	"github.com/heraque/alloydb-autoscaler/internal/resilience"
+	"github.com/heraque/alloydb-autoscaler/internal/state"
+	"github.com/heraque/alloydb-autoscaler/internal/telemetry"
+	alloydbapi "google.golang.org/api/alloydb/v1"
 )
 
-// ScaleUp aumenta o número de réplicas em 1, se possível
-func ScaleUp(ctx context.Context) error {
-	startTime := time.Now()
+// actionNames descreve as ações de escala nas mensagens de erro
+var actionNames = map[string]string{
+	"scaleUp":   "scale up",
+	"scaleDown": "scale down",
+}
 
-	currentCount, err := alloydb.GetReadPoolNodeCount(ctx)
-	if err != nil {
-		return err
-	}
+// completedMessages são as mensagens de log de operações concluídas
+var completedMessages = map[string]string{
+	"scaleUp":   "Scale up operation completed successfully",
+	"scaleDown": "Scale down operation completed successfully",
+}
 
-	if currentCount < config.Get().MaxReplicas {
-		newCount := currentCount + 1
+// ScaleUp aumenta o número de réplicas conforme o ScaleStep do alvo, limitado a
+// MaxReplicas, em um único PATCH. breach é o quanto as métricas ultrapassaram
+// o limite, usado pela política proporcional. Em modo dry-run apenas registra
+// o PATCH que seria enviado.
+func ScaleUp(ctx context.Context, api alloydb.InstanceAPI, snapshot alloydb.InstanceSnapshot, target config.Target, breach float64, reason string) error {
+	startTime := time.Now()
+	currentCount := snapshot.NodeCount
 
-		log.Info().
+	if currentCount >= target.MaxReplicas {
+		log.Warn().
 			Str("component", "scaling").
 			Str("action", "scaleUp").
-			Str("instance", config.Get().InstanceName).
+			Str("instance", target.InstanceName).
 			Int("currentReplicas", currentCount).
-			Int("targetReplicas", newCount).
-			Int("maxReplicas", config.Get().MaxReplicas).
-			Msg("Initiating scale up operation")
+			Int("maxReplicas", target.MaxReplicas).
+			Msg("Maximum replica count reached, cannot scale up further")
+		return nil
+	}
 
-		operation, err := alloydb.UpdateReplicaCount(ctx, newCount)
-		if err != nil {
-			return err
-		}
+	step := target.ScaleStep.Step(currentCount, breach)
+	newCount := min(currentCount+step, target.MaxReplicas)
 
-		err = alloydb.WaitForOperation(ctx, operation)
-		if err != nil {
-			return fmt.Errorf("error waiting for scale up operation to complete: %w", err)
-		}
+	log.Info().
+		Str("component", "scaling").
+		Str("action", "scaleUp").
+		Str("instance", target.InstanceName).
+		Int("currentReplicas", currentCount).
+		Int("targetReplicas", newCount).
+		Int("step", step).
+		Str("stepPolicy", target.ScaleStep.String()).
+		Int("maxReplicas", target.MaxReplicas).
+		Msg("Initiating scale up operation")
 
-		log.Info().
-			Str("component", "scaling").
-			Str("action", "scaleUp").
-			Str("instance", config.Get().InstanceName).
-			Int("newReplicaCount", newCount).
-			Dur("duration", time.Since(startTime).Round(time.Second)).
-			Msg("Scale up operation completed successfully")
-	} else {
+	return apply(ctx, api, snapshot, target, "scaleUp", currentCount, newCount, reason, startTime)
+}
+
+// ScaleDown diminui o número de réplicas conforme o ScaleStep do alvo, limitado
+// a MinReplicas, em um único PATCH. Para a política proporcional, breach é o
+// quanto as métricas estão abaixo do limite. Em modo dry-run apenas registra o
+// PATCH que seria enviado.
+func ScaleDown(ctx context.Context, api alloydb.InstanceAPI, snapshot alloydb.InstanceSnapshot, target config.Target, breach float64, reason string) error {
+	startTime := time.Now()
+	currentCount := snapshot.NodeCount
+
+	if currentCount <= target.MinReplicas {
 		log.Warn().
 			Str("component", "scaling").
-			Str("action", "scaleUp").
-			Str("instance", config.Get().InstanceName).
+			Str("action", "scaleDown").
+			Str("instance", target.InstanceName).
 			Int("currentReplicas", currentCount).
-			Int("maxReplicas", config.Get().MaxReplicas).
-			Msg("Maximum replica count reached, cannot scale up further")
+			Int("minReplicas", target.MinReplicas).
+			Msg("Minimum replica count reached, cannot scale down further")
+		return nil
 	}
-	return nil
+
+	step := target.ScaleStep.Step(currentCount, breach)
+	newCount := max(currentCount-step, target.MinReplicas)
+
+	log.Info().
+		Str("component", "scaling").
+		Str("action", "scaleDown").
+		Str("instance", target.InstanceName).
+		Int("currentReplicas", currentCount).
+		Int("targetReplicas", newCount).
+		Int("step", step).
+		Str("stepPolicy", target.ScaleStep.String()).
+		Int("minReplicas", target.MinReplicas).
+		Msg("Initiating scale down operation")
+
+	return apply(ctx, api, snapshot, target, "scaleDown", currentCount, newCount, reason, startTime)
 }
 
-// ScaleDown diminui o número de réplicas em 1, se possível
-func ScaleDown(ctx context.Context) error {
+// ScaleTo ajusta o read pool para exatamente count nós em um único PATCH,
+// sem passar pelo ScaleStep. Usado pelo target tracking e quando um
+// agendamento ou a previsão exige um número de nós fora dos limites atuais.
+func ScaleTo(ctx context.Context, api alloydb.InstanceAPI, snapshot alloydb.InstanceSnapshot, target config.Target, count int, reason string) error {
 	startTime := time.Now()
+	currentCount := snapshot.NodeCount
+	if currentCount == count {
+		return nil
+	}
 
-	currentCount, err := alloydb.GetReadPoolNodeCount(ctx)
-	if err != nil {
+	action := "scaleUp"
+	if count < currentCount {
+		action = "scaleDown"
+	}
+	log.Info().
+		Str("component", "scaling").
+		Str("action", action).
+		Str("instance", target.InstanceName).
+		Int("currentReplicas", currentCount).
+		Int("targetReplicas", count).
+		Str("reason", reason).
+		Msg("Initiating scale operation to a fixed replica count")
+	return apply(ctx, api, snapshot, target, action, currentCount, count, reason, startTime)
+}
+
+// apply envia o novo número de nós, ou apenas o registra em modo dry-run
+func apply(ctx context.Context, api alloydb.InstanceAPI, snapshot alloydb.InstanceSnapshot, target config.Target, action string, currentCount, newCount int, reason string, startTime time.Time) error {
+	telemetry.SetDesiredReadPoolNodes(target.Name, newCount)
+	if target.DryRun {
+		recordDryRun(target, action, currentCount, newCount, reason)
+		recordCompletion(target.Name, action, time.Now())
+		return nil
+	}
+
+	if err := applyReplicaCount(ctx, api, snapshot, target, action, newCount); err != nil {
 		return err
 	}
 
-	if currentCount > config.Get().MinReplicas {
-		newCount := currentCount - 1
+	log.Info().
+		Str("component", "scaling").
+		Str("action", action).
+		Str("instance", target.InstanceName).
+		Int("newReplicaCount", newCount).
+		Dur("duration", time.Since(startTime).Round(time.Second)).
+		Msg(completedMessages[action])
+	telemetry.SetReadPoolNodes(target.Name, newCount)
+	return nil
+}
 
-		log.Info().
+// applyReplicaCount envia o PATCH e aguarda a operação, mantendo-a registrada
+// como pendente até terminar. Se o contexto for cancelado antes (por exemplo,
+// no encerramento do processo), a operação continua registrada para ser
+// retomada pelo próximo processo.
+func applyReplicaCount(ctx context.Context, api alloydb.InstanceAPI, snapshot alloydb.InstanceSnapshot, target config.Target, action string, newCount int) error {
+	operation, err := alloydb.UpdateReplicaCount(ctx, api, snapshot, newCount)
+	if err != nil {
+		return err
+	}
+
+	pending := state.PendingOperation{
+		Target:      target.Name,
+		Instance:    target.InstancePath(),
+		Operation:   operation.Name,
+		Action:      action,
+		TargetCount: newCount,
+		StartedAt:   time.Now(),
+	}
+	if err := state.AddPending(pending); err != nil {
+		log.Error(err).
 			Str("component", "scaling").
-			Str("action", "scaleDown").
-			Str("instance", config.Get().InstanceName).
-			Int("currentReplicas", currentCount).
-			Int("targetReplicas", newCount).
-			Int("minReplicas", config.Get().MinReplicas).
-			Msg("Initiating scale down operation")
+			Str("action", action).
+			Str("target", target.Name).
+			Str("operationName", operation.Name).
+			Msg("Failed to record pending operation")
+	}
 
-		operation, err := alloydb.UpdateReplicaCount(ctx, newCount)
-		if err != nil {
-			return err
+	err = waitPending(ctx, api, pending)
+	telemetry.ObserveScaleOperation(target.Name, action, time.Since(pending.StartedAt), err)
+	if err != nil {
+		return fmt.Errorf("error waiting for %s operation to complete: %w", actionNames[action], err)
+	}
+	return nil
+}
+
+// waitPending aguarda uma operação pendente e a remove do registro apenas
+// quando ela termina, com sucesso ou falha, ou deixa de existir. Operações
+// cuja espera foi interrompida, seja pelo contexto, por exceder
+// OPERATION_MAX_WAIT_SECONDS ou por erro na consulta, podem continuar em
+// andamento e permanecem registradas. Operações concluídas com sucesso
+// iniciam o cooldown do alvo.
+func waitPending(ctx context.Context, api alloydb.InstanceAPI, pending state.PendingOperation) error {
+	completedAt, err := alloydb.WaitForOperation(ctx, api, &alloydbapi.Operation{Name: pending.Operation})
+	finished := err == nil || errors.Is(err, alloydb.ErrOperationFailed) || resilience.Classify(err) == resilience.ClassNotFound
+	if !finished {
+		msg := "Operation still running, recorded as pending for the next process"
+		if !state.Persistent() {
+			msg = "Operation still running and STATE_FILE is not set; it will not be resumed"
 		}
+		log.Warn().
+			Str("component", "scaling").
+			Str("action", pending.Action).
+			Str("target", pending.Target).
+			Str("operationName", pending.Operation).
+			Int("targetReplicas", pending.TargetCount).
+			Str("reason", err.Error()).
+			Msg(msg)
+		return err
+	}
 
-		err = alloydb.WaitForOperation(ctx, operation)
-		if err != nil {
-			return fmt.Errorf("error waiting for scale down operation to complete: %w", err)
+	if rmErr := state.RemovePending(pending.Operation); rmErr != nil {
+		log.Error(rmErr).
+			Str("component", "scaling").
+			Str("action", pending.Action).
+			Str("target", pending.Target).
+			Str("operationName", pending.Operation).
+			Msg("Failed to clear pending operation")
+	}
+	if err == nil {
+		recordCompletion(pending.Target, pending.Action, completedAt)
+	}
+	return err
+}
+
+// ResumePending aguarda as operações deixadas pendentes por um processo
+// anterior antes que o alvo volte a tomar decisões. Além das registradas em
+// STATE_FILE, aguarda as que ainda estão em andamento na instância, como as
+// de um processo sem STATE_FILE ou as iniciadas pelo console ou pelo
+// Terraform, para não enviar um PATCH que conflite com elas.
+func ResumePending(ctx context.Context, api alloydb.InstanceAPI, target config.Target) error {
+	for _, pending := range state.Pending(target.Name) {
+		if pending.Instance != target.InstancePath() {
+			// O alvo passou a apontar para outra instância; a operação antiga não se aplica
+			_ = state.RemovePending(pending.Operation)
+			continue
 		}
 
 		log.Info().
 			Str("component", "scaling").
-			Str("action", "scaleDown").
-			Str("instance", config.Get().InstanceName).
-			Int("newReplicaCount", newCount).
-			Dur("duration", time.Since(startTime).Round(time.Second)).
-			Msg("Scale down operation completed successfully")
-	} else {
-		log.Warn().
+			Str("action", "resume").
+			Str("target", target.Name).
+			Str("operationName", pending.Operation).
+			Int("targetReplicas", pending.TargetCount).
+			Time("startedAt", pending.StartedAt).
+			Msg("Resuming pending operation from a previous process")
+
+		if err := waitPending(ctx, api, pending); err != nil {
+			return fmt.Errorf("error waiting for pending %s operation: %w", actionNames[pending.Action], err)
+		}
+	}
+
+	running, err := alloydb.ListRunningOperations(ctx, api, target.InstancePath())
+	if err != nil {
+		return err
+	}
+	for _, operation := range running {
+		log.Info().
 			Str("component", "scaling").
-			Str("action", "scaleDown").
-			Str("instance", config.Get().InstanceName).
-			Int("currentReplicas", currentCount).
-			Int("minReplicas", config.Get().MinReplicas).
-			Msg("Minimum replica count reached, cannot scale down further")
+			Str("action", "resume").
+			Str("target", target.Name).
+			Str("operationName", operation.Name).
+			Msg("Operation running on the instance, waiting for it before the first cycle")
+
+		if _, err := alloydb.WaitForOperation(ctx, api, operation); err != nil {
+			return fmt.Errorf("error waiting for running operation %s: %w", operation.Name, err)
+		}
 	}
 	return nil
 }
@@ -0,0 +1,301 @@
+package scaling
+
+import (
+	"context"
+	"errors"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/heraque/alloydb-autoscaler/internal/alloydb"
+	"github.com/heraque/alloydb-autoscaler/internal/config"
+	"github.com/heraque/alloydb-autoscaler/internal/state"
+)
+
+func TestMain(m *testing.M) {
+	alloydb.OperationPollInterval = 0
+	config.Set(config.Config{OperationMaxWaitSeconds: 60})
+	if err := state.Open(""); err != nil {
+		panic(err)
+	}
+	os.Exit(m.Run())
+}
+
+// newTarget retorna um alvo com limites de 1 a 5 nós registrado no Fake
+func newTarget(t *testing.T, api *alloydb.Fake, nodes int) config.Target {
+	t.Helper()
+	t.Cleanup(func() { Forget(t.Name()) })
+	target := config.Target{
+		Name:         t.Name(),
+		GCPProject:   "project",
+		Region:       "region",
+		ClusterName:  "cluster",
+		InstanceName: "read-pool",
+		MinReplicas:  1,
+		MaxReplicas:  5,
+	}
+	if err := api.AddInstance(target.InstancePath(), nodes, 2); err != nil {
+		t.Fatal(err)
+	}
+	return target
+}
+
+// newSnapshot lê o snapshot da instância do alvo no Fake
+func newSnapshot(t *testing.T, api *alloydb.Fake, target config.Target) alloydb.InstanceSnapshot {
+	t.Helper()
+	snapshot, err := alloydb.GetSnapshot(context.Background(), api, target)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return snapshot
+}
+
+// parseStep interpreta a política de passo de um caso de teste
+func parseStep(t *testing.T, value string) config.StepPolicy {
+	t.Helper()
+	step, err := config.ParseStepPolicy(value)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return step
+}
+
+func TestScaleUp(t *testing.T) {
+	tests := []struct {
+		name    string
+		nodes   int
+		step    string
+		breach  float64
+		want    int
+		patches int
+	}{
+		{name: "one more node", nodes: 2, want: 3, patches: 1},
+		{name: "fixed step", nodes: 1, step: "2", want: 3, patches: 1},
+		{name: "step limited to max", nodes: 4, step: "3", want: 5, patches: 1},
+		{name: "breach tier", nodes: 1, step: "10:1,40:3", breach: 50, want: 4, patches: 1},
+		{name: "already at max", nodes: 5, want: 5},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			api := alloydb.NewFake()
+			target := newTarget(t, api, tt.nodes)
+			target.ScaleStep = parseStep(t, tt.step)
+
+			if err := ScaleUp(context.Background(), api, newSnapshot(t, api, target), target, tt.breach, "test"); err != nil {
+				t.Fatalf("ScaleUp() error = %v", err)
+			}
+			if got := api.NodeCount(target.InstancePath()); got != tt.want {
+				t.Errorf("node count = %d, want %d", got, tt.want)
+			}
+			if got := len(api.Patches()); got != tt.patches {
+				t.Errorf("patches = %d, want %d", got, tt.patches)
+			}
+		})
+	}
+}
+
+func TestScaleDown(t *testing.T) {
+	tests := []struct {
+		name    string
+		nodes   int
+		step    string
+		breach  float64
+		want    int
+		patches int
+	}{
+		{name: "one less node", nodes: 3, want: 2, patches: 1},
+		{name: "percent step", nodes: 4, step: "50%", want: 2, patches: 1},
+		{name: "step limited to min", nodes: 3, step: "5", want: 1, patches: 1},
+		{name: "already at min", nodes: 1, want: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			api := alloydb.NewFake()
+			target := newTarget(t, api, tt.nodes)
+			target.ScaleStep = parseStep(t, tt.step)
+
+			if err := ScaleDown(context.Background(), api, newSnapshot(t, api, target), target, tt.breach, "test"); err != nil {
+				t.Fatalf("ScaleDown() error = %v", err)
+			}
+			if got := api.NodeCount(target.InstancePath()); got != tt.want {
+				t.Errorf("node count = %d, want %d", got, tt.want)
+			}
+			if got := len(api.Patches()); got != tt.patches {
+				t.Errorf("patches = %d, want %d", got, tt.patches)
+			}
+		})
+	}
+}
+
+func TestScaleUpOperationFailure(t *testing.T) {
+	api := alloydb.NewFake()
+	target := newTarget(t, api, 2)
+	target.ScaleUpCooldown = 60
+	api.FailNextOperation("internal error")
+
+	if err := ScaleUp(context.Background(), api, newSnapshot(t, api, target), target, 0, "test"); err == nil {
+		t.Fatal("ScaleUp() error = nil, want the operation failure")
+	}
+	if got := api.NodeCount(target.InstancePath()); got != 2 {
+		t.Errorf("node count = %d, want 2", got)
+	}
+	if _, active := ActiveCooldown(target, "scaleDown"); active {
+		t.Error("failed operation started a cooldown")
+	}
+	if pending := state.Pending(target.Name); len(pending) != 0 {
+		t.Errorf("pending operations = %v, want none", pending)
+	}
+}
+
+// startOperation envia um PATCH para count nós que só termina após latency no
+// relógio do Fake, sem registrá-lo como pendente
+func startOperation(t *testing.T, api *alloydb.Fake, snapshot alloydb.InstanceSnapshot, count int) string {
+	t.Helper()
+	operation, err := alloydb.UpdateReplicaCount(context.Background(), api, snapshot, count)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return operation.Name
+}
+
+func TestResumePending(t *testing.T) {
+	tests := []struct {
+		name string
+		// failure faz a operação terminar com erro
+		failure string
+		// missing registra uma operação que não existe na API
+		missing      bool
+		wantErr      bool
+		wantNodes    int
+		wantCooldown bool
+	}{
+		{name: "finished operation", wantNodes: 3, wantCooldown: true},
+		{name: "failed operation", failure: "internal error", wantErr: true, wantNodes: 2},
+		{name: "operation no longer exists", missing: true, wantErr: true, wantNodes: 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			api := alloydb.NewFake()
+			target := newTarget(t, api, 2)
+			target.ScaleUpCooldown = 60
+			snapshot := newSnapshot(t, api, target)
+
+			operation := "projects/project/locations/region/operations/missing"
+			if !tt.missing {
+				if tt.failure != "" {
+					api.FailNextOperation(tt.failure)
+				}
+				operation = startOperation(t, api, snapshot, 3)
+			}
+			if err := state.AddPending(state.PendingOperation{
+				Target:      target.Name,
+				Instance:    target.InstancePath(),
+				Operation:   operation,
+				Action:      "scaleUp",
+				TargetCount: 3,
+			}); err != nil {
+				t.Fatal(err)
+			}
+
+			err := ResumePending(context.Background(), api, target)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ResumePending() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if pending := state.Pending(target.Name); len(pending) != 0 {
+				t.Errorf("pending operations = %v, want none once the operation finished", pending)
+			}
+			if got := api.NodeCount(target.InstancePath()); got != tt.wantNodes {
+				t.Errorf("node count = %d, want %d", got, tt.wantNodes)
+			}
+			if _, active := ActiveCooldown(target, "scaleDown"); active != tt.wantCooldown {
+				t.Errorf("cooldown active = %v, want %v", active, tt.wantCooldown)
+			}
+		})
+	}
+}
+
+func TestResumePendingKeepsUnfinishedOperation(t *testing.T) {
+	interval := alloydb.OperationPollInterval
+	previous := config.Get()
+	t.Cleanup(func() {
+		alloydb.OperationPollInterval = interval
+		config.Set(previous)
+	})
+	alloydb.OperationPollInterval = 10 * time.Millisecond
+	c := previous
+	c.OperationMaxWaitSeconds = 1
+	config.Set(c)
+
+	api := alloydb.NewFake()
+	api.OperationLatency = time.Hour
+	target := newTarget(t, api, 2)
+	snapshot := newSnapshot(t, api, target)
+	operation := startOperation(t, api, snapshot, 3)
+	pending := state.PendingOperation{
+		Target:      target.Name,
+		Instance:    target.InstancePath(),
+		Operation:   operation,
+		Action:      "scaleUp",
+		TargetCount: 3,
+	}
+	if err := state.AddPending(pending); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = state.RemovePending(operation) })
+
+	err := ResumePending(context.Background(), api, target)
+	if !errors.Is(err, alloydb.ErrOperationMaxWait) {
+		t.Fatalf("ResumePending() error = %v, want ErrOperationMaxWait", err)
+	}
+	if got := state.Pending(target.Name); len(got) != 1 || got[0].Operation != operation {
+		t.Errorf("pending operations = %v, want %s kept for the next process", got, operation)
+	}
+}
+
+func TestResumePendingOtherInstance(t *testing.T) {
+	api := alloydb.NewFake()
+	target := newTarget(t, api, 2)
+	if err := state.AddPending(state.PendingOperation{
+		Target:    target.Name,
+		Instance:  "projects/project/locations/region/clusters/cluster/instances/previous-pool",
+		Operation: "projects/project/locations/region/operations/previous",
+		Action:    "scaleUp",
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := ResumePending(context.Background(), api, target); err != nil {
+		t.Fatalf("ResumePending() error = %v", err)
+	}
+	if pending := state.Pending(target.Name); len(pending) != 0 {
+		t.Errorf("pending operations = %v, want the other instance's operation dropped", pending)
+	}
+	if calls := api.Calls(alloydb.MethodGetOperation); calls != 0 {
+		t.Errorf("GetOperation calls = %d, want none", calls)
+	}
+}
+
+func TestResumePendingWaitsForRunningOperation(t *testing.T) {
+	api := alloydb.NewFake()
+	// Cada chamada ao Fake avança o relógio um minuto; a operação leva cinco
+	now := time.Now()
+	api.Now = func() time.Time {
+		now = now.Add(time.Minute)
+		return now
+	}
+	api.OperationLatency = 5 * time.Minute
+	target := newTarget(t, api, 2)
+	snapshot := newSnapshot(t, api, target)
+	// Iniciada fora do autoscaler: não há registro pendente
+	startOperation(t, api, snapshot, 4)
+
+	if err := ResumePending(context.Background(), api, target); err != nil {
+		t.Fatalf("ResumePending() error = %v", err)
+	}
+	if got := api.NodeCount(target.InstancePath()); got != 4 {
+		t.Errorf("node count = %d, want 4 after waiting for the running operation", got)
+	}
+	if calls := api.Calls(alloydb.MethodGetOperation); calls == 0 {
+		t.Error("running operation was not polled")
+	}
+}
@@ -0,0 +1,61 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/heraque/alloydb-autoscaler/internal/health"
+	"github.com/heraque/alloydb-autoscaler/internal/log"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// shutdownTimeout limita a espera por requisições em andamento ao encerrar
+const shutdownTimeout = 5 * time.Second
+
+// newMux registra os endpoints de métricas e health checks
+func newMux() *http.ServeMux {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.Handler())
+	mux.Handle("/healthz", health.LivenessHandler())
+	mux.Handle("/readyz", health.ReadinessHandler())
+	return mux
+}
+
+// Start inicia o servidor HTTP de métricas e health checks em addr e o encerra quando
+// o contexto for cancelado
+func Start(ctx context.Context, addr string) {
+	srv := &http.Server{
+		Addr:              addr,
+		Handler:           newMux(),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	go func() {
+		log.Info().
+			Str("component", "server").
+			Str("action", "start").
+			Str("addr", addr).
+			Msg("HTTP server listening")
+		if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Error(err).
+				Str("component", "server").
+				Str("action", "start").
+				Str("addr", addr).
+				Msg("HTTP server failed")
+		}
+	}()
+
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancel()
+		if err := srv.Shutdown(shutdownCtx); err != nil {
+			log.Error(err).
+				Str("component", "server").
+				Str("action", "stop").
+				Msg("HTTP server shutdown failed")
+		}
+	}()
+}
@@ -0,0 +1,60 @@
+package server
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/heraque/alloydb-autoscaler/internal/config"
+	"github.com/heraque/alloydb-autoscaler/internal/health"
+	"github.com/heraque/alloydb-autoscaler/internal/telemetry"
+)
+
+func TestEndpoints(t *testing.T) {
+	target := config.Target{Name: t.Name(), CheckInterval: 60}
+	previous := config.Get()
+	config.Set(config.Config{LivenessMultiplier: 3, ReadinessMultiplier: 3, Targets: []config.Target{target}})
+	t.Cleanup(func() {
+		config.Set(previous)
+		health.Forget(target.Name)
+		telemetry.ForgetTarget(target.Name)
+	})
+	// O alvo acabou de iniciar: vivo, mas sem coleta de métricas
+	health.TargetStarted(target.Name)
+	telemetry.SetVotes(target.Name, 2, 0)
+
+	srv := httptest.NewServer(newMux())
+	defer srv.Close()
+
+	tests := []struct {
+		path     string
+		want     int
+		wantBody string
+	}{
+		{path: "/healthz", want: http.StatusOK, wantBody: `"healthy":true`},
+		{path: "/readyz", want: http.StatusServiceUnavailable, wantBody: "no successful metrics collection yet"},
+		{path: "/metrics", want: http.StatusOK, wantBody: `alloydb_autoscaler_votes{direction="up",target="` + target.Name + `"} 2`},
+		{path: "/unknown", want: http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(strings.TrimPrefix(tt.path, "/"), func(t *testing.T) {
+			resp, err := http.Get(srv.URL + tt.path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer resp.Body.Close()
+			body, err := io.ReadAll(resp.Body)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if resp.StatusCode != tt.want {
+				t.Errorf("GET %s status = %d, want %d", tt.path, resp.StatusCode, tt.want)
+			}
+			if !strings.Contains(string(body), tt.wantBody) {
+				t.Errorf("GET %s body = %s, want it to contain %s", tt.path, body, tt.wantBody)
+			}
+		})
+	}
+}
@@ -0,0 +1,152 @@
+package state
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// PendingOperation é uma operação de escala iniciada e ainda não confirmada
+// como concluída. Fica registrada até terminar para que outro processo possa
+// retomá-la após um reinício.
+type PendingOperation struct {
+	Target      string    `json:"target"`
+	Instance    string    `json:"instance"`
+	Operation   string    `json:"operation"`
+	Action      string    `json:"action"`
+	TargetCount int       `json:"targetCount"`
+	StartedAt   time.Time `json:"startedAt"`
+}
+
+// fileState é o conteúdo persistido no arquivo de estado
+type fileState struct {
+	PendingOperations []PendingOperation `json:"pendingOperations"`
+}
+
+var (
+	mu      sync.Mutex
+	path    string
+	current fileState
+)
+
+// Open carrega o arquivo de estado. Com path vazio o estado fica apenas em
+// memória e operações pendentes não sobrevivem a reinícios.
+func Open(statePath string) error {
+	mu.Lock()
+	defer mu.Unlock()
+
+	path = statePath
+	current = fileState{}
+	if path == "" {
+		return nil
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("error reading state file %s: %w", path, err)
+	}
+	if len(data) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(data, &current); err != nil {
+		return fmt.Errorf("error decoding state file %s: %w", path, err)
+	}
+	return nil
+}
+
+// Persistent informa se o estado está sendo gravado em arquivo
+func Persistent() bool {
+	mu.Lock()
+	defer mu.Unlock()
+	return path != ""
+}
+
+// Pending retorna as operações pendentes do alvo
+func Pending(target string) []PendingOperation {
+	mu.Lock()
+	defer mu.Unlock()
+
+	var ops []PendingOperation
+	for _, op := range current.PendingOperations {
+		if op.Target == target {
+			ops = append(ops, op)
+		}
+	}
+	return ops
+}
+
+// AddPending registra uma operação em andamento
+func AddPending(op PendingOperation) error {
+	mu.Lock()
+	defer mu.Unlock()
+
+	current.PendingOperations = append(removeOperation(current.PendingOperations, op.Operation), op)
+	return save()
+}
+
+// RemovePending remove uma operação concluída
+func RemovePending(operation string) error {
+	mu.Lock()
+	defer mu.Unlock()
+
+	current.PendingOperations = removeOperation(current.PendingOperations, operation)
+	return save()
+}
+
+// Flush grava o estado atual no arquivo
+func Flush() error {
+	mu.Lock()
+	defer mu.Unlock()
+	return save()
+}
+
+func removeOperation(ops []PendingOperation, operation string) []PendingOperation {
+	kept := ops[:0:0]
+	for _, op := range ops {
+		if op.Operation != operation {
+			kept = append(kept, op)
+		}
+	}
+	return kept
+}
+
+// save grava o arquivo de forma atômica (arquivo temporário + rename)
+func save() error {
+	if path == "" {
+		return nil
+	}
+
+	data, err := json.MarshalIndent(current, "", "  ")
+	if err != nil {
+		return fmt.Errorf("error encoding state: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return fmt.Errorf("error creating state file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("error writing state file: %w", err)
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("error syncing state file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("error closing state file: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("error replacing state file: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,138 @@
+package state
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+)
+
+// openTemp abre um arquivo de estado em um diretório temporário e volta ao
+// estado em memória ao fim do teste
+func openTemp(t *testing.T) string {
+	t.Helper()
+	statePath := filepath.Join(t.TempDir(), "state.json")
+	t.Cleanup(func() { _ = Open("") })
+	if err := Open(statePath); err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	return statePath
+}
+
+func operationNames(ops []PendingOperation) []string {
+	names := make([]string, len(ops))
+	for i, op := range ops {
+		names[i] = op.Operation
+	}
+	return names
+}
+
+func TestPendingSurvivesReopen(t *testing.T) {
+	statePath := openTemp(t)
+	started := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	ops := []PendingOperation{
+		{Target: "reports", Instance: "read-pool", Operation: "operation-1", Action: "scale_up", TargetCount: 3, StartedAt: started},
+		{Target: "reports", Instance: "read-pool", Operation: "operation-2", Action: "scale_up", TargetCount: 4, StartedAt: started},
+		{Target: "billing", Instance: "other-pool", Operation: "operation-3", Action: "scale_down", TargetCount: 1, StartedAt: started},
+	}
+	for _, op := range ops {
+		if err := AddPending(op); err != nil {
+			t.Fatalf("AddPending() error = %v", err)
+		}
+	}
+	// Registrar de novo a mesma operação a substitui
+	if err := AddPending(ops[0]); err != nil {
+		t.Fatal(err)
+	}
+	if err := RemovePending("operation-2"); err != nil {
+		t.Fatalf("RemovePending() error = %v", err)
+	}
+
+	if err := Open(statePath); err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	if !Persistent() {
+		t.Error("Persistent() = false with a state file")
+	}
+	got := Pending("reports")
+	if len(got) != 1 || got[0] != ops[0] {
+		t.Errorf("Pending(reports) = %+v, want [%+v]", got, ops[0])
+	}
+	if got := operationNames(Pending("billing")); !slices.Equal(got, []string{"operation-3"}) {
+		t.Errorf("Pending(billing) = %v, want [operation-3]", got)
+	}
+
+	// A gravação atômica não deixa arquivos temporários para trás
+	entries, err := os.ReadDir(filepath.Dir(statePath))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 || entries[0].Name() != "state.json" {
+		names := make([]string, len(entries))
+		for i, entry := range entries {
+			names[i] = entry.Name()
+		}
+		t.Errorf("state directory = %v, want only state.json", names)
+	}
+}
+
+func TestOpen(t *testing.T) {
+	tests := []struct {
+		name        string
+		content     string
+		missing     bool
+		wantPending []string
+		wantErr     bool
+	}{
+		{name: "missing file", missing: true},
+		{name: "empty file", content: ""},
+		{
+			name:        "pending operations",
+			content:     `{"pendingOperations": [{"target": "reports", "operation": "operation-1"}]}`,
+			wantPending: []string{"operation-1"},
+		},
+		{name: "corrupt file", content: `{"pendingOperations": [`, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			statePath := filepath.Join(t.TempDir(), "state.json")
+			if !tt.missing {
+				if err := os.WriteFile(statePath, []byte(tt.content), 0o600); err != nil {
+					t.Fatal(err)
+				}
+			}
+			t.Cleanup(func() { _ = Open("") })
+
+			err := Open(statePath)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Open() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got := operationNames(Pending("reports")); !slices.Equal(got, tt.wantPending) {
+				t.Errorf("Pending() = %v, want %v", got, tt.wantPending)
+			}
+			if _, err := os.Stat(statePath); tt.missing && !os.IsNotExist(err) {
+				t.Errorf("Open() created the missing state file")
+			}
+		})
+	}
+}
+
+func TestInMemory(t *testing.T) {
+	t.Cleanup(func() { _ = Open("") })
+	if err := Open(""); err != nil {
+		t.Fatal(err)
+	}
+	if Persistent() {
+		t.Error("Persistent() = true without a state file")
+	}
+	if err := AddPending(PendingOperation{Target: "reports", Operation: "operation-1"}); err != nil {
+		t.Fatalf("AddPending() error = %v", err)
+	}
+	if got := operationNames(Pending("reports")); !slices.Equal(got, []string{"operation-1"}) {
+		t.Errorf("Pending() = %v, want [operation-1]", got)
+	}
+	if err := Flush(); err != nil {
+		t.Errorf("Flush() error = %v", err)
+	}
+}
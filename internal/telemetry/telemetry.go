@@ -0,0 +1,199 @@
+package telemetry
+
+import (
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+const namespace = "alloydb_autoscaler"
+
+// Decisões registradas em DecisionsTotal
+const (
+	DecisionScaleUp   = "scale_up"
+	DecisionScaleDown = "scale_down"
+	DecisionMaintain  = "maintain"
+)
+
+var (
+	leader = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "leader",
+		Help:      "1 when this process is the leader and evaluates targets, 0 when standing by.",
+	})
+
+	readPoolNodes = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "read_pool_nodes",
+		Help:      "Current number of read pool nodes observed on the instance.",
+	}, []string{"target"})
+
+	desiredReadPoolNodes = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "read_pool_desired_nodes",
+		Help:      "Number of read pool nodes the autoscaler last decided on.",
+	}, []string{"target"})
+
+	forecastReplicas = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "forecast_replicas",
+		Help:      "Read pool nodes required by the predictive forecast.",
+	}, []string{"target"})
+
+	ruleValue = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "rule_value",
+		Help:      "Last value observed for each scaling rule, after unit conversion.",
+	}, []string{"target", "rule"})
+
+	votes = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "votes",
+		Help:      "Votes accumulated in the current evaluation window.",
+	}, []string{"target", "direction"})
+
+	decisions = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "decisions_total",
+		Help:      "Scaling decisions taken at the end of each evaluation window.",
+	}, []string{"target", "decision"})
+
+	scaleOperationDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "scale_operation_duration_seconds",
+		Help:      "Time spent waiting for read pool update operations to finish.",
+		Buckets:   []float64{30, 60, 120, 300, 600, 900, 1200, 1800, 3600},
+	}, []string{"target", "action", "result"})
+
+	apiRequestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "api_request_duration_seconds",
+		Help:      "Latency of Cloud Monitoring and AlloyDB API calls.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"api", "method"})
+
+	apiErrors = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "api_errors_total",
+		Help:      "Cloud Monitoring and AlloyDB API calls that returned an error.",
+	}, []string{"api", "method"})
+
+	circuitOpen = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "circuit_breaker_open",
+		Help:      "1 while the circuit breaker of a GCP API is open or half-open, 0 when closed.",
+	}, []string{"api"})
+)
+
+var (
+	// lastNodes guarda o último número de nós observado por alvo, usado como
+	// número desejado quando a decisão é manter
+	lastNodesMu sync.Mutex
+	lastNodes   = make(map[string]int)
+)
+
+// Sem eleição de líder o processo é sempre o líder
+func init() {
+	leader.Set(1)
+}
+
+// SetLeader registra se este processo detém a liderança
+func SetLeader(isLeader bool) {
+	if isLeader {
+		leader.Set(1)
+		return
+	}
+	leader.Set(0)
+}
+
+// SetReadPoolNodes registra o número atual de nós do read pool
+func SetReadPoolNodes(target string, count int) {
+	readPoolNodes.WithLabelValues(target).Set(float64(count))
+
+	lastNodesMu.Lock()
+	lastNodes[target] = count
+	lastNodesMu.Unlock()
+}
+
+// SetDesiredReadPoolNodes registra o número de nós decidido pelo autoscaler
+func SetDesiredReadPoolNodes(target string, count int) {
+	desiredReadPoolNodes.WithLabelValues(target).Set(float64(count))
+}
+
+// SetForecastReplicas registra o número de nós previsto pela escala preditiva
+func SetForecastReplicas(target string, replicas int) {
+	forecastReplicas.WithLabelValues(target).Set(float64(replicas))
+}
+
+// SetRuleValue registra o último valor observado de uma regra de escala
+func SetRuleValue(target, rule string, value float64) {
+	ruleValue.WithLabelValues(target, rule).Set(value)
+}
+
+// SetVotes registra os votos acumulados na janela de avaliação
+func SetVotes(target string, scaleUp, scaleDown int) {
+	votes.WithLabelValues(target, "up").Set(float64(scaleUp))
+	votes.WithLabelValues(target, "down").Set(float64(scaleDown))
+}
+
+// RecordDecision contabiliza uma decisão de escala. Ao manter, o número
+// desejado passa a ser o último número de nós observado.
+func RecordDecision(target, decision string) {
+	decisions.WithLabelValues(target, decision).Inc()
+
+	if decision == DecisionMaintain {
+		lastNodesMu.Lock()
+		count, ok := lastNodes[target]
+		lastNodesMu.Unlock()
+		if ok {
+			SetDesiredReadPoolNodes(target, count)
+		}
+	}
+}
+
+// ObserveScaleOperation registra a duração de uma operação de escala
+func ObserveScaleOperation(target, action string, duration time.Duration, err error) {
+	scaleOperationDuration.WithLabelValues(target, action, result(err)).Observe(duration.Seconds())
+}
+
+// ObserveAPICall registra a latência e, em caso de erro, a falha de uma chamada de API
+func ObserveAPICall(api, method string, start time.Time, err error) {
+	apiRequestDuration.WithLabelValues(api, method).Observe(time.Since(start).Seconds())
+	if err != nil {
+		apiErrors.WithLabelValues(api, method).Inc()
+	}
+}
+
+// SetCircuitOpen registra se o circuit breaker de uma API está aberto
+func SetCircuitOpen(api string, open bool) {
+	if open {
+		circuitOpen.WithLabelValues(api).Set(1)
+		return
+	}
+	circuitOpen.WithLabelValues(api).Set(0)
+}
+
+// ForgetTarget remove as séries de um alvo que deixou de ser gerenciado
+func ForgetTarget(target string) {
+	labels := prometheus.Labels{"target": target}
+	readPoolNodes.DeletePartialMatch(labels)
+	desiredReadPoolNodes.DeletePartialMatch(labels)
+	ruleValue.DeletePartialMatch(labels)
+	forecastReplicas.DeletePartialMatch(labels)
+	votes.DeletePartialMatch(labels)
+	decisions.DeletePartialMatch(labels)
+	scaleOperationDuration.DeletePartialMatch(labels)
+
+	lastNodesMu.Lock()
+	delete(lastNodes, target)
+	lastNodesMu.Unlock()
+}
+
+func result(err error) string {
+	if err != nil {
+		return "error"
+	}
+	return "success"
+}
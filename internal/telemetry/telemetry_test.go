@@ -0,0 +1,83 @@
+package telemetry
+
+import (
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+// value lê o valor atual de um gauge ou counter
+func value(t *testing.T, metric prometheus.Metric) float64 {
+	t.Helper()
+	var m dto.Metric
+	if err := metric.Write(&m); err != nil {
+		t.Fatal(err)
+	}
+	if m.Counter != nil {
+		return m.Counter.GetValue()
+	}
+	return m.Gauge.GetValue()
+}
+
+func TestSetVotes(t *testing.T) {
+	target := t.Name()
+	t.Cleanup(func() { ForgetTarget(target) })
+
+	for _, tt := range []struct{ up, down int }{{2, 0}, {0, 3}} {
+		SetVotes(target, tt.up, tt.down)
+		up := value(t, votes.WithLabelValues(target, "up"))
+		down := value(t, votes.WithLabelValues(target, "down"))
+		if up != float64(tt.up) || down != float64(tt.down) {
+			t.Errorf("votes = up %g, down %g, want up %d, down %d", up, down, tt.up, tt.down)
+		}
+	}
+}
+
+func TestReplicaGauges(t *testing.T) {
+	tests := []struct {
+		name        string
+		nodes       int
+		desired     int
+		decision    string
+		wantDesired float64
+	}{
+		{name: "scale up", nodes: 2, desired: 3, decision: DecisionScaleUp, wantDesired: 3},
+		{name: "maintain uses the observed nodes", nodes: 2, desired: 3, decision: DecisionMaintain, wantDesired: 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			target := t.Name()
+			t.Cleanup(func() { ForgetTarget(target) })
+
+			SetReadPoolNodes(target, tt.nodes)
+			SetDesiredReadPoolNodes(target, tt.desired)
+			RecordDecision(target, tt.decision)
+
+			if got := value(t, readPoolNodes.WithLabelValues(target)); got != float64(tt.nodes) {
+				t.Errorf("read_pool_nodes = %g, want %d", got, tt.nodes)
+			}
+			if got := value(t, desiredReadPoolNodes.WithLabelValues(target)); got != tt.wantDesired {
+				t.Errorf("read_pool_desired_nodes = %g, want %g", got, tt.wantDesired)
+			}
+			if got := value(t, decisions.WithLabelValues(target, tt.decision)); got != 1 {
+				t.Errorf("decisions_total = %g, want 1", got)
+			}
+		})
+	}
+}
+
+func TestForgetTarget(t *testing.T) {
+	target := t.Name()
+	SetReadPoolNodes(target, 2)
+	SetVotes(target, 1, 0)
+	RecordDecision(target, DecisionMaintain)
+
+	ForgetTarget(target)
+	if got := votes.DeletePartialMatch(map[string]string{"target": target}); got != 0 {
+		t.Errorf("%d vote series kept after ForgetTarget", got)
+	}
+	if readPoolNodes.DeleteLabelValues(target) || desiredReadPoolNodes.DeleteLabelValues(target) {
+		t.Error("replica series kept after ForgetTarget")
+	}
+}